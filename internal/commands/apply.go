@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/manager"
+	"github.com/jschneider/agenthelper/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var desiredFile string
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Install, update and uninstall tools to match agents.yaml",
+	Long: `Converge the installed tools to the desired state declared in agents.yaml.
+
+The desired state is read from ./agents.yaml and ~/.agenthelper/agents.yaml, where the
+project file overrides the user file per tool. Example:
+
+  methods: [npm, brew]
+  tools:
+    claude-code: present
+    aider:
+      version: "~0.50"
+      method: pip
+    copilot-cli: absent
+
+Examples:
+  agenthelper apply
+  agenthelper apply --file team/agents.yaml`,
+	Args: cobra.NoArgs,
+	Run:  runApply,
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show how the installed tools differ from agents.yaml",
+	Long: `Show which tools 'agenthelper apply' would install, update or uninstall to
+match the desired state in agents.yaml.`,
+	Args: cobra.NoArgs,
+	Run:  runDiff,
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Exit non-zero when the installed tools differ from agents.yaml",
+	Long: `Compare the installed tools with the desired state in agents.yaml and exit
+with status 1 when they differ, e.g. in CI.`,
+	Args: cobra.NoArgs,
+	Run:  runCheck,
+}
+
+// DriftOutput represents a tool's drift in JSON
+type DriftOutput struct {
+	Key          string `json:"key"`
+	Name         string `json:"name"`
+	State        string `json:"state"`
+	Version      string `json:"version,omitempty"`
+	InstalledVer string `json:"installed_version,omitempty"`
+	Action       string `json:"action,omitempty"`
+	TargetVer    string `json:"target_version,omitempty"`
+	Method       string `json:"method,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(checkCmd)
+	for _, cmd := range []*cobra.Command{applyCmd, diffCmd, checkCmd} {
+		cmd.Flags().StringVarP(&desiredFile, "file", "f", "", "desired state file (default ./agents.yaml and ~/.agenthelper/agents.yaml)")
+	}
+	applyCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, yesUsage)
+}
+
+func loadDrifts(cmd *cobra.Command) []*manager.Drift {
+	state, err := config.LoadDesiredState(desiredFile)
+	if err != nil {
+		ui.Error("%v", err)
+		os.Exit(1)
+	}
+
+	var spinner *ui.Spinner
+	if !viper.GetBool("json") {
+		spinner = ui.NewSpinner("Comparing installed tools...")
+		spinner.Start()
+	}
+	drifts := newManager().Diff(cmd.Context(), state)
+	if spinner != nil {
+		spinner.Stop()
+	}
+	return drifts
+}
+
+func runApply(cmd *cobra.Command, args []string) {
+	drifts := loadDrifts(cmd)
+	if countDrift(drifts) == 0 {
+		if viper.GetBool("json") {
+			printOperationsJSON([]OperationOutput{})
+			return
+		}
+		ui.Success("All tools match the desired state")
+		return
+	}
+
+	results := newManager().Apply(cmd.Context(), drifts)
+
+	if viper.GetBool("json") {
+		outputs := []OperationOutput{}
+		failed := false
+		for _, d := range drifts {
+			r, ok := results[d.Tool.Key]
+			if !ok {
+				continue
+			}
+			out := OperationOutput{Key: d.Tool.Key, Name: d.Tool.Name, Success: r.Success, Output: r.Output, Log: r.Log}
+			if r.Error != nil {
+				out.Error = r.Error.Error()
+			}
+			failed = failed || !r.Success
+			outputs = append(outputs, out)
+		}
+		printOperationsJSON(outputs)
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	failCount := 0
+	fmt.Println()
+	for _, d := range drifts {
+		r, ok := results[d.Tool.Key]
+		if !ok {
+			continue
+		}
+		if r.Success {
+			ui.Print("  %s %s: %s", ui.Green(ui.SymbolSuccess), d.Tool.Name, r.Output)
+		} else {
+			failCount++
+			ui.Print("  %s %s: %s failed: %v", ui.Red(ui.SymbolError), d.Tool.Name, r.Action, r.Error)
+		}
+	}
+
+	fmt.Println()
+	ui.Info("Summary: %d changed, %d failed", len(results)-failCount, failCount)
+	if failCount > 0 {
+		os.Exit(1)
+	}
+}
+
+func runDiff(cmd *cobra.Command, args []string) {
+	printDrifts(loadDrifts(cmd))
+}
+
+func runCheck(cmd *cobra.Command, args []string) {
+	drifts := loadDrifts(cmd)
+	printDrifts(drifts)
+	if countDrift(drifts) > 0 {
+		os.Exit(1)
+	}
+}
+
+// countDrift returns the number of tools that are not in their desired state
+func countDrift(drifts []*manager.Drift) int {
+	n := 0
+	for _, d := range drifts {
+		if d.Action != "" || d.Error != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func printDrifts(drifts []*manager.Drift) {
+	if viper.GetBool("json") {
+		outputs := make([]DriftOutput, len(drifts))
+		for i, d := range drifts {
+			outputs[i] = DriftOutput{
+				Key:          d.Tool.Key,
+				Name:         d.Tool.Name,
+				State:        d.Desired.State,
+				Version:      d.Desired.Version,
+				InstalledVer: d.InstalledVer,
+				Action:       d.Action,
+				TargetVer:    d.TargetVer,
+				Method:       d.Method,
+				Reason:       d.Reason,
+			}
+			if d.Error != nil {
+				outputs[i].Error = d.Error.Error()
+			}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(outputs)
+		return
+	}
+
+	table := ui.NewTable([]string{"Tool", "Desired", "Installed", "Action"})
+	for _, d := range drifts {
+		desired := d.Desired.State
+		if d.Desired.Version != "" {
+			desired = d.Desired.Version
+		}
+		action := ui.Green(ui.SymbolSuccess + " ok")
+		switch {
+		case d.Error != nil:
+			action = ui.Red(fmt.Sprintf("%s %s: %v", ui.SymbolError, d.Action, d.Error))
+		case d.Action != "":
+			action = ui.Yellow(fmt.Sprintf("%s %s", ui.SymbolUpdate, d.Action))
+			if d.TargetVer != "" {
+				action += " v" + d.TargetVer
+			}
+			if d.Reason != "" {
+				action += " (" + d.Reason + ")"
+			}
+		}
+		table.AddRow([]string{d.Tool.Name, desired, orDash(d.InstalledVer), action})
+	}
+	table.Render()
+
+	if n := countDrift(drifts); n > 0 {
+		fmt.Println()
+		ui.Warn("%d of %d tools differ from the desired state", n, len(drifts))
+	}
+}
@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/manager"
+	"github.com/jschneider/agenthelper/internal/platform"
+	"github.com/jschneider/agenthelper/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	bundleTools  []string
+	bundleOS     string
+	bundleArch   string
+	bundleOutput string
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Provision machines without internet access",
+	Long: `Download the packages of tools on a connected machine into a single archive,
+and install them from it on machines without internet access.
+
+A bundle contains npm tarballs, pip wheels with their dependencies, or release
+assets (.deb, AppImage) for one platform, and a manifest with their checksums.`,
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Download tools into a bundle",
+	Long: `Download the latest allowed version of each tool for a platform into a bundle.
+
+Examples:
+  agenthelper bundle create --tools claude-code,aider --os linux --arch amd64 -o bundle.tar.gz
+  agenthelper bundle create`,
+	Args: cobra.NoArgs,
+	Run:  runBundleCreate,
+}
+
+var bundleInstallCmd = &cobra.Command{
+	Use:   "install <bundle> [tool...]",
+	Short: "Install the tools in a bundle",
+	Long: `Verify the checksums of a bundle and install its tools, or only the given ones.
+Tools already installed at the bundled version are skipped.
+
+Examples:
+  agenthelper bundle install bundle.tar.gz
+  agenthelper bundle install bundle.tar.gz aider`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runBundleInstall,
+}
+
+// bundleCreateOutput is the JSON output of bundle create
+type bundleCreateOutput struct {
+	Path     string                  `json:"path,omitempty"`
+	Manifest *manager.BundleManifest `json:"manifest,omitempty"`
+	Failed   map[string]string       `json:"failed,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleInstallCmd)
+
+	current := platform.Current()
+	bundleCreateCmd.Flags().StringSliceVarP(&bundleTools, "tools", "t", nil, "tools to bundle (default all)")
+	bundleCreateCmd.Flags().StringVar(&bundleOS, "os", string(current.OS), "target operating system (linux, darwin, windows)")
+	bundleCreateCmd.Flags().StringVar(&bundleArch, "arch", string(current.Arch), "target architecture (amd64, arm64, 386)")
+	bundleCreateCmd.Flags().StringVarP(&bundleOutput, "output", "o", "", "bundle file (default agenthelper-bundle-<os>-<arch>.tar.gz)")
+}
+
+func runBundleCreate(cmd *cobra.Command, args []string) {
+	target := &platform.Platform{OS: platform.OS(bundleOS), Arch: platform.Arch(bundleArch)}
+	switch target.OS {
+	case platform.Linux, platform.Darwin, platform.Windows:
+	default:
+		ui.Error("Unknown operating system: %s", bundleOS)
+		os.Exit(1)
+	}
+	switch target.Arch {
+	case platform.AMD64, platform.ARM64, platform.I386:
+	default:
+		ui.Error("Unknown architecture: %s", bundleArch)
+		os.Exit(1)
+	}
+
+	tools := config.GetAllTools()
+	if len(bundleTools) > 0 {
+		tools = nil
+		for _, key := range bundleTools {
+			tool, ok := config.GetTool(strings.ToLower(strings.TrimSpace(key)))
+			if !ok {
+				ui.Error("Unknown tool: %s", key)
+				os.Exit(1)
+			}
+			tools = append(tools, *tool)
+		}
+	}
+
+	output := bundleOutput
+	if output == "" {
+		output = fmt.Sprintf("agenthelper-bundle-%s-%s.tar.gz", target.OS, target.Arch)
+	}
+
+	result := newManager().CreateBundle(cmd.Context(), tools, target, output)
+
+	if viper.GetBool("json") {
+		out := bundleCreateOutput{Manifest: result.Manifest}
+		if result.Success {
+			out.Path = result.Path
+		}
+		if len(result.Failed) > 0 {
+			out.Failed = make(map[string]string)
+			for key, err := range result.Failed {
+				out.Failed[key] = err.Error()
+			}
+		}
+		if result.Error != nil {
+			out.Error = result.Error.Error()
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(out)
+		if !result.Success {
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Println()
+	keys := make([]string, 0, len(result.Failed))
+	for key := range result.Failed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ui.Print("  %s %s: %v", ui.Red(ui.SymbolError), toolName(key), result.Failed[key])
+	}
+	if !result.Success {
+		ui.Error("Could not create bundle: %v", result.Error)
+		os.Exit(1)
+	}
+	for _, entry := range result.Manifest.Tools {
+		ui.Print("  %s %s: v%s (%s, %s)", ui.Green(ui.SymbolSuccess), toolName(entry.Key), entry.Version, entry.Method, entry.File)
+	}
+
+	fmt.Println()
+	ui.Success("Created %s with %d tools for %s", result.Path, len(result.Manifest.Tools), target.String())
+}
+
+func runBundleInstall(cmd *cobra.Command, args []string) {
+	var keys []string
+	for _, arg := range args[1:] {
+		keys = append(keys, strings.ToLower(arg))
+	}
+
+	results, err := newManager().InstallBundle(cmd.Context(), args[0], keys)
+	if err != nil {
+		ui.Error("%v", err)
+		os.Exit(1)
+	}
+
+	if viper.GetBool("json") {
+		outputs := []OperationOutput{}
+		for key, result := range results {
+			outputs = append(outputs, installOutput(key, result))
+		}
+		printOperationsJSON(outputs)
+		return
+	}
+
+	sorted := make([]string, 0, len(results))
+	for key := range results {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	failCount := 0
+	fmt.Println()
+	for _, key := range sorted {
+		result := results[key]
+		if result.Success {
+			ui.Print("  %s %s: %s", ui.Green(ui.SymbolSuccess), toolName(key), result.Output)
+		} else {
+			failCount++
+			ui.Print("  %s %s: %v", ui.Red(ui.SymbolError), toolName(key), result.Error)
+		}
+	}
+
+	fmt.Println()
+	ui.Info("Summary: %d succeeded, %d failed", len(results)-failCount, failCount)
+	if failCount > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var catalogKeyFile string
+
+var catalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Manage the signed tool catalog",
+	Long: `Manage an additional tool catalog that is fetched from a URL or a local
+directory and merged on top of the built-in tool definitions.
+
+Configure it in the application config file (~/.agenthelper.yaml):
+
+  catalog:
+    url: https://example.com/agenthelper/tools.yaml   # or file:///mnt/catalog
+    public_key: <base64 ed25519 public key>
+    refresh: 24h
+
+The catalog must be accompanied by a detached signature (<url>.sig) created
+with 'agenthelper catalog sign'.`,
+}
+
+var catalogRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Fetch and verify the catalog, bypassing the cache",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := config.RefreshCatalog(); err != nil {
+			ui.Error("%v", err)
+			os.Exit(1)
+		}
+		ui.Success("Catalog refreshed from %s", config.GetCatalogSettings().URL)
+	},
+}
+
+var catalogKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate a key pair for signing catalogs",
+	Long: `Generate an ed25519 key pair. The private key is written to the given file;
+the public key is printed for use as catalog.public_key.
+
+Examples:
+  agenthelper catalog keygen --key catalog.key`,
+	Args: cobra.NoArgs,
+	Run:  runCatalogKeygen,
+}
+
+var catalogSignCmd = &cobra.Command{
+	Use:   "sign <file>",
+	Short: "Sign a catalog file",
+	Long: `Create a detached signature <file>.sig for a catalog file.
+
+Examples:
+  agenthelper catalog sign tools.yaml --key catalog.key`,
+	Args: cobra.ExactArgs(1),
+	Run:  runCatalogSign,
+}
+
+func init() {
+	rootCmd.AddCommand(catalogCmd)
+	catalogCmd.AddCommand(catalogRefreshCmd)
+	catalogCmd.AddCommand(catalogKeygenCmd)
+	catalogCmd.AddCommand(catalogSignCmd)
+	catalogKeygenCmd.Flags().StringVarP(&catalogKeyFile, "key", "k", "catalog.key", "private key file")
+	catalogSignCmd.Flags().StringVarP(&catalogKeyFile, "key", "k", "catalog.key", "private key file")
+}
+
+func runCatalogKeygen(cmd *cobra.Command, args []string) {
+	if _, err := os.Stat(catalogKeyFile); err == nil {
+		ui.Error("%s already exists", catalogKeyFile)
+		os.Exit(1)
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		ui.Error("Failed to generate key: %v", err)
+		os.Exit(1)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(privateKey) + "\n"
+	if err := os.WriteFile(catalogKeyFile, []byte(encoded), 0600); err != nil {
+		ui.Error("Failed to write private key: %v", err)
+		os.Exit(1)
+	}
+
+	ui.Success("Private key written to %s", catalogKeyFile)
+	fmt.Printf("Public key: %s\n", base64.StdEncoding.EncodeToString(publicKey))
+}
+
+func runCatalogSign(cmd *cobra.Command, args []string) {
+	keyData, err := os.ReadFile(catalogKeyFile)
+	if err != nil {
+		ui.Error("Failed to read private key: %v", err)
+		os.Exit(1)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(keyData)))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		ui.Error("%s is not a valid ed25519 private key", catalogKeyFile)
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		ui.Error("Failed to read catalog: %v", err)
+		os.Exit(1)
+	}
+	if problems := config.ValidateData(args[0], data); len(problems) > 0 {
+		for _, problem := range problems {
+			ui.Error("%s", problem.Error())
+		}
+		os.Exit(1)
+	}
+
+	signature := config.SignCatalog(data, ed25519.PrivateKey(key))
+	if err := os.WriteFile(args[0]+".sig", signature, 0644); err != nil {
+		ui.Error("Failed to write signature: %v", err)
+		os.Exit(1)
+	}
+
+	ui.Success("Signature written to %s.sig", args[0])
+}
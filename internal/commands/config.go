@@ -0,0 +1,208 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var showOrigin bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect tool configuration",
+	Long: `Inspect the tool definitions loaded from all configuration layers.
+
+Tool definitions are merged by key from, in order of precedence:
+  1. the --config file
+  2. ./tools.yaml and ./config/tools.yaml (project)
+  3. ~/.agenthelper/tools.yaml (user)
+  4. the signed tool catalog, if configured (catalog)
+  5. the built-in defaults (embedded)`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [tool]",
+	Short: "Show the merged tool definitions",
+	Long: `Show the merged tool definitions.
+
+With --origin, every field is listed together with the layer it came from.
+
+Examples:
+  agenthelper config show
+  agenthelper config show claude-code --origin`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runConfigShow,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return config.GetConfiguredToolKeys(), cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Validate tool definition files",
+	Long: `Validate a tool definition file, or every configuration layer and their
+merged result if no file is given.
+
+Reports unknown fields, duplicate keys, invalid version patterns, unknown
+version source types and OS keys, and install specs without methods.
+Exits with a non-zero status if any problem is found.
+
+Examples:
+  agenthelper config validate
+  agenthelper config validate ~/.agenthelper/tools.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runConfigValidate,
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for tool definition files",
+	Long: `Print a JSON Schema for tool definition files, generated from the Go types.
+
+Point your editor at it to lint custom tools.yaml files, e.g. with the YAML
+language server:
+  # yaml-language-server: $schema=./tools.schema.json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(config.JSONSchema())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configSchemaCmd)
+	configShowCmd.Flags().BoolVar(&showOrigin, "origin", false, "show which layer each field came from")
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) {
+	var problems []config.ValidationError
+	var checked []string
+
+	if len(args) > 0 {
+		var err error
+		problems, err = config.ValidateFile(args[0])
+		if err != nil {
+			ui.Error("%v", err)
+			os.Exit(1)
+		}
+		checked = append(checked, args[0])
+	} else {
+		layers, err := config.DiscoverLayers(cfgFile)
+		if err != nil {
+			ui.Error("%v", err)
+			os.Exit(1)
+		}
+		problems = config.ValidateLayers(layers)
+		for i := range layers {
+			checked = append(checked, layers[i].Describe())
+		}
+	}
+
+	if viper.GetBool("json") {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(problems)
+	} else {
+		for _, problem := range problems {
+			ui.Error("%s", problem.Error())
+		}
+		if len(problems) == 0 {
+			ui.Success("No problems found in %s", strings.Join(checked, ", "))
+		}
+	}
+
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) {
+	keys := config.GetConfiguredToolKeys()
+	if len(args) > 0 {
+		key := strings.ToLower(args[0])
+		if config.GetToolOrigins(key) == nil {
+			ui.Error("Unknown tool: %s", key)
+			os.Exit(1)
+		}
+		keys = []string{key}
+	}
+
+	if showOrigin {
+		showConfigOrigins(keys)
+		return
+	}
+
+	var tools []config.ToolDefinition
+	for _, key := range keys {
+		if tool, ok := config.GetTool(key); ok {
+			tools = append(tools, *tool)
+		}
+	}
+
+	if viper.GetBool("json") {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(tools)
+		return
+	}
+
+	data, err := yaml.Marshal(config.Config{Tools: tools})
+	if err != nil {
+		ui.Error("Failed to encode configuration: %v", err)
+		os.Exit(1)
+	}
+	fmt.Print(string(data))
+}
+
+func showConfigOrigins(keys []string) {
+	if viper.GetBool("json") {
+		origins := make(map[string][]config.FieldOrigin)
+		for _, key := range keys {
+			origins[key] = config.GetToolOrigins(key)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(origins)
+		return
+	}
+
+	ui.Print("%s Layers", ui.Bold("●"))
+	for _, layer := range config.LoadedLayers {
+		ui.Print("  %-12s %s", layer.Name, layer.Describe())
+	}
+	fmt.Println()
+
+	for _, key := range keys {
+		title := key
+		if _, ok := config.GetTool(key); !ok {
+			title += " " + ui.Yellow("(disabled)")
+		}
+		ui.Print("%s %s", ui.Bold("●"), title)
+
+		table := ui.NewTable([]string{"Field", "Value", "Origin"})
+		for _, field := range config.GetToolOrigins(key) {
+			origin := field.Layer
+			if field.File != "" {
+				origin = fmt.Sprintf("%s (%s)", field.Layer, field.File)
+			}
+			table.AddRow([]string{field.Path, field.Value, origin})
+		}
+		table.Render()
+		fmt.Println()
+	}
+}
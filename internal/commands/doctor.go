@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jschneider/agenthelper/internal/manager"
+	"github.com/jschneider/agenthelper/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check for duplicate and shadowed tool installations",
+	Long: `Find every copy of each tool's command on PATH and report its version and
+where it came from. Flags tools whose copy that runs is not the one agenthelper
+manages, or is older than another copy, e.g. a stale nvm node version shadowing
+the global install.
+
+Exits with status 1 when a tool has more than one copy.`,
+	Args: cobra.NoArgs,
+	Run:  runDoctor,
+}
+
+// DoctorOutput represents a tool with several copies on PATH in JSON
+type DoctorOutput struct {
+	Key           string               `json:"key"`
+	Name          string               `json:"name"`
+	Installations []InstallationOutput `json:"installations"`
+	Problems      []string             `json:"problems"`
+}
+
+// InstallationOutput represents one copy of a tool in JSON
+type InstallationOutput struct {
+	Path     string `json:"path"`
+	Resolved string `json:"resolved,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Method   string `json:"method,omitempty"`
+	Origin   string `json:"origin"`
+	Active   bool   `json:"active"`
+	Managed  bool   `json:"managed"`
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) {
+	var spinner *ui.Spinner
+	if !viper.GetBool("json") {
+		spinner = ui.NewSpinner("Looking for duplicate installations...")
+		spinner.Start()
+	}
+	reports := newManager().CheckInstallations(cmd.Context())
+	if spinner != nil {
+		spinner.Stop()
+	}
+
+	if viper.GetBool("json") {
+		outputs := make([]DoctorOutput, len(reports))
+		for i, r := range reports {
+			outputs[i] = DoctorOutput{Key: r.Tool.Key, Name: r.Tool.Name, Problems: r.Problems}
+			for _, inst := range r.Installations {
+				out := InstallationOutput{
+					Path:    inst.Path,
+					Version: inst.Version,
+					Method:  inst.Method,
+					Origin:  inst.Origin,
+					Active:  inst.Active,
+					Managed: inst.Managed,
+				}
+				if inst.Resolved != inst.Path {
+					out.Resolved = inst.Resolved
+				}
+				outputs[i].Installations = append(outputs[i].Installations, out)
+			}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(outputs)
+	} else {
+		printInstallationReports(reports)
+	}
+
+	if len(reports) > 0 {
+		os.Exit(1)
+	}
+}
+
+func printInstallationReports(reports []*manager.InstallationReport) {
+	if len(reports) == 0 {
+		ui.Success("No duplicate or shadowed installations found")
+		return
+	}
+
+	for _, r := range reports {
+		fmt.Println()
+		ui.Print("%s", ui.Bold(r.Tool.Name))
+		table := ui.NewTable([]string{"#", "Path", "Version", "Origin", ""})
+		for i, inst := range r.Installations {
+			var marks []string
+			if inst.Active {
+				marks = append(marks, "runs")
+			}
+			if inst.Managed {
+				marks = append(marks, "managed")
+			}
+			table.AddRow([]string{
+				fmt.Sprintf("%d", i+1),
+				inst.Path,
+				orDash(inst.Version),
+				inst.Origin,
+				strings.Join(marks, ", "),
+			})
+		}
+		table.Render()
+		for _, p := range r.Problems {
+			ui.Warn("%s", p)
+		}
+	}
+
+	fmt.Println()
+	ui.Info("Remove the copies you don't need, or reorder PATH so the right one runs first")
+}
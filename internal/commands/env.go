@@ -6,7 +6,6 @@ import (
 	"os"
 
 	"github.com/jschneider/agenthelper/internal/config"
-	"github.com/jschneider/agenthelper/internal/manager"
 	"github.com/jschneider/agenthelper/internal/platform"
 	"github.com/jschneider/agenthelper/internal/ui"
 	"github.com/spf13/cobra"
@@ -135,7 +134,7 @@ func buildEnvReport() *EnvReport {
 	}
 
 	for _, p := range prerequisites {
-		available := manager.CommandExists(p.command)
+		available := platform.LocalSystem().HasCommand(p.command)
 		report.Prerequisites = append(report.Prerequisites, PrerequisiteInfo{
 			Name:      p.name,
 			Available: available,
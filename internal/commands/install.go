@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/jschneider/agenthelper/internal/manager"
 	"github.com/jschneider/agenthelper/internal/ui"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
@@ -15,16 +17,19 @@ var (
 )
 
 var installCmd = &cobra.Command{
-	Use:   "install <tool|all>",
+	Use:   "install <tool[@version]|all>",
 	Short: "Install a coding tool",
 	Long: `Install a coding tool by its key name.
 
+Append @<version> to install a specific version, e.g. to roll back a bad release.
 Use 'agenthelper install all' to install all available tools.
 
 Examples:
   agenthelper install claude-code
+  agenthelper install claude-code@1.0.30
   agenthelper install aider --method pip
-  agenthelper install all --method winget`,
+  agenthelper install all --method winget
+  agenthelper install all --dry-run`,
 	Args: cobra.ExactArgs(1),
 	Run:  runInstall,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -37,15 +42,18 @@ Examples:
 
 func init() {
 	rootCmd.AddCommand(installCmd)
-	installCmd.Flags().StringVarP(&installMethod, "method", "m", "", "preferred install method (winget, brew, npm, pip, apt)")
+	installCmd.Flags().StringVarP(&installMethod, "method", "m", "", "preferred install method ("+strings.Join(config.InstallMethods, ", ")+")")
+	installCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be installed without changing anything")
+	installCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, yesUsage)
 }
 
 func runInstall(cmd *cobra.Command, args []string) {
-	toolKey := strings.ToLower(args[0])
-	mgr := manager.NewManager()
+	ctx := cmd.Context()
+	toolKey, toolVersion := manager.ParseToolVersion(strings.ToLower(args[0]))
+	mgr := newManager()
 
 	if toolKey == "all" {
-		runInstallAll(mgr)
+		runInstallAll(ctx, mgr)
 		return
 	}
 
@@ -59,16 +67,38 @@ func runInstall(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if dryRun {
+		plans := mgr.PlanDependencies(ctx, tool)
+		printPlans(append(plans, mgr.PlanInstall(ctx, tool, installMethod, toolVersion)))
+		return
+	}
+
 	// Check if already installed
-	if version, err := mgr.GetInstalledVersion(tool); err == nil {
-		ui.Warn("%s is already installed (v%s)", tool.Name, version)
-		fmt.Println("Use 'agenthelper update' to update to the latest version.")
+	if version, err := mgr.GetInstalledVersion(ctx, tool); err == nil {
+		if toolVersion == "" || manager.SameVersion(version, toolVersion) {
+			if viper.GetBool("json") {
+				printOperationsJSON([]OperationOutput{installOutput(tool.Key, &manager.InstallResult{Success: true, Output: "Already installed"})})
+				return
+			}
+			ui.Warn("%s is already installed (v%s)", tool.Name, version)
+			fmt.Println("Use 'agenthelper update' to update to the latest version.")
+			return
+		}
+	}
+
+	if !installDependencies(ctx, mgr, tool) {
 		return
 	}
 
 	// Install
 	var result *manager.InstallResult
-	if installMethod != "" {
+	if toolVersion != "" {
+		if installMethod != "" && !mgr.IsMethodAvailable(tool, installMethod) {
+			ui.Error("Install method %s not available for %s", installMethod, tool.Name)
+			return
+		}
+		result = mgr.InstallVersion(ctx, tool, installMethod, toolVersion)
+	} else if installMethod != "" {
 		// Check if tool has any install methods
 		bestMethod, _ := mgr.GetBestInstallMethod(tool)
 		if bestMethod == "" {
@@ -82,26 +112,19 @@ func runInstall(cmd *cobra.Command, args []string) {
 			ui.Error("Install method %s not available for %s on this platform", installMethod, tool.Name)
 			return
 		}
-		var command string
-		switch installMethod {
-		case "winget":
-			command = spec.WinGet
-		case "brew":
-			command = spec.Brew
-		case "npm":
-			command = spec.Npm
-		case "pip":
-			command = spec.Pip
-		case "apt":
-			command = spec.Apt
-		}
-		if command == "" {
+		command := spec.ForMethod(installMethod)
+		if command == "" || !mgr.IsMethodAvailable(tool, installMethod) {
 			ui.Error("Install method %s not available for %s", installMethod, tool.Name)
 			return
 		}
-		result = mgr.InstallWithMethod(tool, installMethod, command)
+		result = mgr.InstallWithMethod(ctx, tool, installMethod, command)
 	} else {
-		result = mgr.Install(tool)
+		result = mgr.Install(ctx, tool)
+	}
+
+	if viper.GetBool("json") {
+		printOperationsJSON([]OperationOutput{installOutput(tool.Key, result)})
+		return
 	}
 
 	if result.Success {
@@ -111,9 +134,23 @@ func runInstall(cmd *cobra.Command, args []string) {
 	}
 }
 
-func runInstallAll(mgr *manager.Manager) {
+func runInstallAll(ctx context.Context, mgr *manager.Manager) {
+	if dryRun {
+		printPlans(mgr.PlanInstallTools(ctx, config.GetAllTools(), installMethod))
+		return
+	}
+
 	ui.Info("Installing all tools...")
-	results := mgr.InstallAll(installMethod)
+	results := mgr.InstallAll(ctx, installMethod)
+
+	if viper.GetBool("json") {
+		var outputs []OperationOutput
+		for key, result := range results {
+			outputs = append(outputs, installOutput(key, result))
+		}
+		printOperationsJSON(outputs)
+		return
+	}
 
 	successCount := 0
 	failCount := 0
@@ -142,6 +179,36 @@ func runInstallAll(mgr *manager.Manager) {
 	ui.Info("Summary: %d succeeded, %d failed", successCount, failCount)
 }
 
+// installDependencies installs the missing tools a tool depends on and reports whether
+// all of them are installed afterwards
+func installDependencies(ctx context.Context, mgr *manager.Manager, tool *config.ToolDefinition) bool {
+	deps := mgr.MissingDependencies(ctx, tool)
+	if len(deps) == 0 {
+		return true
+	}
+
+	ui.Info("Installing dependencies of %s: %s", tool.Name, manager.DescribeTools(deps))
+	results := mgr.InstallTools(ctx, deps, "")
+	ok := true
+	for _, dep := range deps {
+		result, done := results[dep.Key]
+		if !done {
+			ok = false
+			continue
+		}
+		if result.Success {
+			ui.Print("  %s %s: %s", ui.Green(ui.SymbolSuccess), dep.Name, result.Output)
+		} else {
+			ok = false
+			ui.Print("  %s %s: %v", ui.Red(ui.SymbolError), dep.Name, result.Error)
+		}
+	}
+	if !ok {
+		ui.Error("Not installing %s because a dependency is missing", tool.Name)
+	}
+	return ok
+}
+
 func getToolKeys() []string {
 	tools := config.GetAllTools()
 	keys := make([]string, len(tools)+1)
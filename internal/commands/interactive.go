@@ -1,11 +1,13 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/jschneider/agenthelper/internal/config"
 	"github.com/jschneider/agenthelper/internal/manager"
@@ -20,18 +22,24 @@ func RunPromptMode() {
 	// Print banner with platform info
 	ui.PrintPromptBanner(version, plat.String())
 
-	// Show initial status
-	refreshStatus()
-
-	// Setup Ctrl+C handler
+	// Ctrl+C cancels the running operation, or quits when idle
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt)
 	go func() {
-		<-sigChan
-		fmt.Println("\nGoodbye!")
-		os.Exit(0)
+		for range sigChan {
+			if cancelOperation() {
+				fmt.Println()
+				ui.Warn("Cancelling...")
+				continue
+			}
+			fmt.Println("\nGoodbye!")
+			os.Exit(0)
+		}
 	}()
 
+	// Show initial status
+	refreshStatus()
+
 	// Main command loop
 	for {
 		input := ui.PromptCommand("agenthelper> ")
@@ -53,6 +61,8 @@ func RunPromptMode() {
 			handleUpdate(args)
 		case "repair", "r":
 			handleRepair(args)
+		case "uninstall":
+			handleUninstall(args)
 		case "run":
 			handleRun(args)
 		case "env", "e":
@@ -66,12 +76,46 @@ func RunPromptMode() {
 	}
 }
 
+// promptOperation holds the cancel function of the operation running in prompt mode
+var promptOperation struct {
+	sync.Mutex
+	cancel context.CancelFunc
+}
+
+// startOperation returns a context that Ctrl+C cancels until the returned func is called
+func startOperation() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	promptOperation.Lock()
+	promptOperation.cancel = cancel
+	promptOperation.Unlock()
+
+	return ctx, func() {
+		promptOperation.Lock()
+		promptOperation.cancel = nil
+		promptOperation.Unlock()
+		cancel()
+	}
+}
+
+// cancelOperation cancels the running operation and reports whether there was one
+func cancelOperation() bool {
+	promptOperation.Lock()
+	defer promptOperation.Unlock()
+	if promptOperation.cancel == nil {
+		return false
+	}
+	promptOperation.cancel()
+	return true
+}
+
 func refreshStatus() {
-	mgr := manager.NewManager()
+	ctx, done := startOperation()
+	defer done()
+	mgr := newManager()
 
 	spinner := ui.NewSpinner("Checking tool status...")
 	spinner.Start()
-	statuses := mgr.GetAllToolStatus()
+	statuses := mgr.GetAllToolStatus(ctx)
 	spinner.Stop()
 
 	displayCompactStatus(statuses)
@@ -99,6 +143,9 @@ func displayCompactStatus(statuses []*manager.ToolStatus) {
 		if s.LatestVer != "" {
 			latest = s.LatestVer
 		}
+		if s.Tool.Version != "" {
+			latest = formatConstrainedVersion(s)
+		}
 
 		table.AddRow([]string{
 			fmt.Sprintf("%s %s", symbol, s.Tool.Name),
@@ -149,7 +196,7 @@ func handleInstall(args []string) {
 		return
 	}
 
-	toolKey := args[0]
+	toolKey, toolVersion := manager.ParseToolVersion(args[0])
 	tool, ok := config.GetTool(toolKey)
 	if !ok {
 		ui.Error("Unknown tool: %s", toolKey)
@@ -157,16 +204,29 @@ func handleInstall(args []string) {
 		return
 	}
 
-	mgr := manager.NewManager()
+	ctx, done := startOperation()
+	defer done()
+	mgr := newManager()
 
 	// Check if already installed
-	if ver, err := mgr.GetInstalledVersion(tool); err == nil {
-		ui.Warn("%s is already installed (v%s)", tool.Name, ver)
+	if ver, err := mgr.GetInstalledVersion(ctx, tool); err == nil {
+		if toolVersion == "" || manager.SameVersion(ver, toolVersion) {
+			ui.Warn("%s is already installed (v%s)", tool.Name, ver)
+			return
+		}
+	}
+
+	if !installDependencies(ctx, mgr, tool) {
 		return
 	}
 
 	ui.Info("Installing %s...", tool.Name)
-	result := mgr.Install(tool)
+	var result *manager.InstallResult
+	if toolVersion != "" {
+		result = mgr.InstallVersion(ctx, tool, "", toolVersion)
+	} else {
+		result = mgr.Install(ctx, tool)
+	}
 	if result.Success {
 		ui.Success("Installed %s: %s", tool.Name, result.Output)
 	} else {
@@ -175,15 +235,20 @@ func handleInstall(args []string) {
 }
 
 func handleUpdate(args []string) {
-	mgr := manager.NewManager()
+	ctx, done := startOperation()
+	defer done()
+	mgr := newManager()
 
 	if len(args) == 0 {
 		// Update all installed tools
 		ui.Info("Updating all installed tools...")
 
+		// Streamed command output would be overdrawn by the spinner
 		spinner := ui.NewSpinner("Checking for updates...")
-		spinner.Start()
-		results := mgr.UpdateAll()
+		if !mgr.StreamsOutput() {
+			spinner.Start()
+		}
+		results := mgr.UpdateAll(ctx)
 		spinner.Stop()
 
 		for key, result := range results {
@@ -200,7 +265,7 @@ func handleUpdate(args []string) {
 		return
 	}
 
-	toolKey := args[0]
+	toolKey, toolVersion := manager.ParseToolVersion(args[0])
 	tool, ok := config.GetTool(toolKey)
 	if !ok {
 		ui.Error("Unknown tool: %s", toolKey)
@@ -209,13 +274,18 @@ func handleUpdate(args []string) {
 	}
 
 	// Check if installed
-	if _, err := mgr.GetInstalledVersion(tool); err != nil {
+	if _, err := mgr.GetInstalledVersion(ctx, tool); err != nil {
 		ui.Error("%s is not installed", tool.Name)
 		return
 	}
 
 	ui.Info("Updating %s...", tool.Name)
-	result := mgr.Update(tool)
+	var result *manager.UpdateResult
+	if toolVersion != "" {
+		result = mgr.UpdateToVersion(ctx, tool, toolVersion)
+	} else {
+		result = mgr.Update(ctx, tool)
+	}
 	if result.Success {
 		if result.WasUpToDate {
 			ui.Info("%s is already up to date", tool.Name)
@@ -241,27 +311,31 @@ func handleRepair(args []string) {
 		return
 	}
 
-	mgr := manager.NewManager()
+	ctx, done := startOperation()
+	defer done()
+	mgr := newManager()
 
 	// Check if installed
-	if _, err := mgr.GetInstalledVersion(tool); err != nil {
+	if _, err := mgr.GetInstalledVersion(ctx, tool); err != nil {
 		ui.Error("%s is not installed", tool.Name)
 		return
 	}
 
-	if !ui.PromptConfirm(fmt.Sprintf("Repair %s? This will uninstall and reinstall", tool.Name)) {
+	if !ui.PromptConfirm(fmt.Sprintf("Repair %s? This will uninstall and reinstall", tool.Name)) || ctx.Err() != nil {
 		return
 	}
 
 	ui.Info("Repairing %s...", tool.Name)
+	ctx = manager.WithOperation(ctx, "repair")
+	method, command := mgr.InstalledMethod(ctx, tool)
 
 	// Uninstall
 	ui.Print("  Uninstalling...")
-	mgr.Uninstall(tool)
+	mgr.Uninstall(ctx, tool)
 
 	// Reinstall
 	ui.Print("  Reinstalling...")
-	result := mgr.Install(tool)
+	result := mgr.InstallWithMethod(ctx, tool, method, command)
 	if result.Success {
 		ui.Success("Repaired %s: %s", tool.Name, result.Output)
 	} else {
@@ -269,6 +343,47 @@ func handleRepair(args []string) {
 	}
 }
 
+func handleUninstall(args []string) {
+	if len(args) == 0 {
+		ui.Warn("Usage: /uninstall <tool-key> [--purge]")
+		return
+	}
+
+	toolKey := args[0]
+	tool, ok := config.GetTool(toolKey)
+	if !ok {
+		ui.Error("Unknown tool: %s", toolKey)
+		listAvailableTools()
+		return
+	}
+	purge := len(args) > 1 && args[1] == "--purge"
+
+	ctx, done := startOperation()
+	defer done()
+	mgr := newManager()
+
+	if _, err := mgr.GetInstalledVersion(ctx, tool); err != nil {
+		ui.Error("%s is not installed", tool.Name)
+		return
+	}
+	warnDependents(ctx, mgr, tool)
+
+	if !ui.PromptConfirm(fmt.Sprintf("Uninstall %s?", tool.Name)) || ctx.Err() != nil {
+		return
+	}
+
+	ui.Info("Uninstalling %s...", tool.Name)
+	result := mgr.Uninstall(ctx, tool)
+	if !result.Success {
+		ui.Error("Failed to uninstall %s: %v", tool.Name, result.Error)
+		return
+	}
+	ui.Success(result.Output)
+	if purge {
+		purgeConfig(tool, false)
+	}
+}
+
 func handleRun(args []string) {
 	if len(args) == 0 {
 		ui.Warn("Usage: /run <tool-key>")
@@ -284,10 +399,13 @@ func handleRun(args []string) {
 		return
 	}
 
-	mgr := manager.NewManager()
+	// The tool receives Ctrl+C itself; it must not quit prompt mode
+	ctx, done := startOperation()
+	defer done()
+	mgr := newManager()
 
 	// Check if installed
-	if _, err := mgr.GetInstalledVersion(tool); err != nil {
+	if _, err := mgr.GetInstalledVersion(ctx, tool); err != nil {
 		ui.Error("%s is not installed", tool.Name)
 		return
 	}
@@ -331,7 +449,7 @@ func showEnvReport() {
 	prereqs := []string{"node", "npm", "python", "pip", "git"}
 	for _, p := range prereqs {
 		status := ui.Red(ui.SymbolError)
-		if manager.CommandExists(p) {
+		if platform.LocalSystem().HasCommand(p) {
 			status = ui.Green(ui.SymbolSuccess)
 		}
 		fmt.Printf("  %s %s\n", status, p)
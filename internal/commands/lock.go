@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/manager"
+	"github.com/jschneider/agenthelper/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var lockFile string
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Pin installed tool versions in a lockfile",
+	Long: `Write a lockfile recording the installed version and install method of
+every configured tool. Commit the lockfile to your repository and run
+'agenthelper sync --locked' on other machines to get the same versions.
+
+Examples:
+  agenthelper lock
+  agenthelper lock --file team/agenthelper.lock`,
+	Args: cobra.NoArgs,
+	Run:  runLock,
+}
+
+func init() {
+	rootCmd.AddCommand(lockCmd)
+	lockCmd.Flags().StringVarP(&lockFile, "file", "f", manager.LockfileName, "lockfile path")
+}
+
+func runLock(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	mgr := newManager()
+
+	var spinner *ui.Spinner
+	if !viper.GetBool("json") {
+		spinner = ui.NewSpinner("Checking installed versions...")
+		spinner.Start()
+	}
+	lock := mgr.GenerateLockfile(ctx)
+	if spinner != nil {
+		spinner.Stop()
+	}
+
+	if err := manager.WriteLockfile(lockFile, lock); err != nil {
+		ui.Error("%v", err)
+		os.Exit(1)
+	}
+
+	if viper.GetBool("json") {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(lock)
+		return
+	}
+
+	locked := 0
+	for _, entry := range lock.Tools {
+		name := entry.Key
+		if tool, ok := config.GetTool(entry.Key); ok {
+			name = tool.Name
+		}
+		if entry.Version == "" {
+			ui.Print("  %s %s: not installed", ui.Yellow(ui.SymbolPending), name)
+			continue
+		}
+		locked++
+		ui.Print("  %s %s: v%s (%s)", ui.Green(ui.SymbolSuccess), name, entry.Version, entry.Method)
+	}
+
+	ui.Success("Locked %d tool(s) in %s", locked, lockFile)
+}
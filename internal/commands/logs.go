@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/manager"
+	"github.com/jschneider/agenthelper/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	logsLast   int
+	logsFailed bool
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [tool]",
+	Short: "Show logs of install, update and uninstall commands",
+	Long: `Show the command line, environment, exit code, timing and full output of
+recent install, update, uninstall and repair commands.
+
+Logs are kept in the data directory; the oldest are deleted as new ones are written.
+
+Examples:
+  agenthelper logs
+  agenthelper logs claude-code --last 1
+  agenthelper logs --failed`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runLogs,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var keys []string
+		for _, t := range config.GetAllTools() {
+			keys = append(keys, t.Key)
+		}
+		return keys, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsCmd.Flags().IntVarP(&logsLast, "last", "n", 3, "number of logs to show (0 for all)")
+	logsCmd.Flags().BoolVar(&logsFailed, "failed", false, "only show failed commands")
+}
+
+func runLogs(cmd *cobra.Command, args []string) {
+	toolKey := ""
+	if len(args) > 0 {
+		toolKey = strings.ToLower(args[0])
+	}
+
+	logs, err := manager.ReadOperationLogs(toolKey, logsFailed, logsLast)
+	if err != nil {
+		ui.Error("%v", err)
+		os.Exit(1)
+	}
+
+	if viper.GetBool("json") {
+		if logs == nil {
+			logs = []manager.OperationLog{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(logs)
+		return
+	}
+
+	if len(logs) == 0 {
+		ui.Info("No logs found in %s", manager.OperationLogDir())
+		return
+	}
+
+	for _, l := range logs {
+		printOperationLog(&l)
+	}
+}
+
+func printOperationLog(l *manager.OperationLog) {
+	operation := l.Operation
+	if l.Step != "" {
+		operation = fmt.Sprintf("%s (%s)", l.Operation, l.Step)
+	}
+	status := ui.Green(ui.SymbolSuccess)
+	if l.Failed() {
+		status = ui.Red(ui.SymbolError)
+	}
+
+	fmt.Println()
+	ui.Print("%s %s %s  %s", status, ui.Bold(l.Tool), operation, l.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	ui.Print("  Method:    %s", l.Method)
+	ui.Print("  Command:   %s", l.Command)
+	if len(l.Env) > 0 {
+		keys := make([]string, 0, len(l.Env))
+		for k := range l.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ui.Print("  Env:       %s=%s", k, l.Env[k])
+		}
+	}
+	ui.Print("  Exit code: %d", l.ExitCode)
+	ui.Print("  Duration:  %s", l.Duration)
+	if l.Error != "" {
+		ui.Print("  Error:     %s", l.Error)
+	}
+	printLogStream("stdout", l.Stdout)
+	printLogStream("stderr", l.Stderr)
+}
+
+func printLogStream(name, text string) {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return
+	}
+	ui.Print("  %s:", name)
+	for _, line := range strings.Split(text, "\n") {
+		ui.Print("    %s", line)
+	}
+}
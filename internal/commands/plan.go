@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jschneider/agenthelper/internal/manager"
+	"github.com/jschneider/agenthelper/internal/ui"
+	"github.com/spf13/viper"
+)
+
+// dryRun and assumeYes are shared by the commands that change tools
+var (
+	dryRun    bool
+	assumeYes bool
+)
+
+// yesUsage describes the --yes flag of commands that may run verified downloads
+const yesUsage = "run verified downloads without asking for confirmation"
+
+// printPlans shows what an operation would do as a table followed by the commands it
+// would run, or as JSON
+func printPlans(plans []*manager.Plan) {
+	if viper.GetBool("json") {
+		if plans == nil {
+			plans = []*manager.Plan{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(plans)
+		return
+	}
+
+	ui.Info("Dry run: nothing will be changed")
+	fmt.Println()
+
+	table := ui.NewTable([]string{"#", "Tool", "Action", "Method", "Current", "Target", "Sudo"})
+	for i, p := range plans {
+		action := p.Action
+		switch {
+		case p.Error != "":
+			action = ui.Red(p.Action + " (fails)")
+		case p.Action == "skip":
+			action = fmt.Sprintf("skip (%s)", p.Reason)
+		}
+		sudo := ""
+		if p.NeedsSudo {
+			sudo = ui.Yellow("yes")
+		}
+		table.AddRow([]string{
+			fmt.Sprintf("%d", i+1),
+			p.Name,
+			action,
+			orDash(p.Method),
+			orDash(p.CurrentVersion),
+			orDash(p.TargetVersion),
+			sudo,
+		})
+	}
+	table.Render()
+
+	fmt.Println()
+	for i, p := range plans {
+		switch {
+		case p.Error != "":
+			ui.Print("  %d. %s %s", i+1, ui.Red(ui.SymbolError), p.Error)
+		case p.Command != "":
+			ui.Print("  %d. %s", i+1, p.Command)
+		}
+		for _, path := range p.Purge {
+			ui.Print("     delete %s", path)
+		}
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -19,24 +20,28 @@ This is useful when a tool is in a broken state.
 
 Examples:
   agenthelper repair claude-code
-  agenthelper repair aider`,
+  agenthelper repair aider
+  agenthelper repair claude-code --dry-run`,
 	Args: cobra.ExactArgs(1),
 	Run:  runRepair,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) != 0 {
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		}
-		return getInstalledToolKeys(), cobra.ShellCompDirectiveNoFileComp
+		return getInstalledToolKeys(cmd.Context()), cobra.ShellCompDirectiveNoFileComp
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(repairCmd)
+	repairCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what the repair would run without changing anything")
+	repairCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, yesUsage)
 }
 
 func runRepair(cmd *cobra.Command, args []string) {
+	ctx := manager.WithOperation(cmd.Context(), "repair")
 	toolKey := strings.ToLower(args[0])
-	mgr := manager.NewManager()
+	mgr := newManager()
 
 	tool, ok := config.GetTool(toolKey)
 	if !ok {
@@ -48,11 +53,19 @@ func runRepair(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if dryRun {
+		printPlans(mgr.PlanRepair(ctx, tool))
+		return
+	}
+
 	ui.Info("Repairing %s...", tool.Name)
 
+	// Reinstall the way the tool was installed, not with whatever method is preferred here
+	method, command := mgr.InstalledMethod(ctx, tool)
+
 	// Step 1: Try to uninstall
 	ui.Print("  Step 1: Uninstalling...")
-	uninstallResult := mgr.Uninstall(tool)
+	uninstallResult := mgr.Uninstall(ctx, tool)
 	if uninstallResult.Success {
 		ui.Print("    %s Uninstalled", ui.Green(ui.SymbolSuccess))
 	} else {
@@ -61,7 +74,7 @@ func runRepair(cmd *cobra.Command, args []string) {
 
 	// Step 2: Reinstall
 	ui.Print("  Step 2: Reinstalling...")
-	installResult := mgr.Install(tool)
+	installResult := mgr.InstallWithMethod(ctx, tool, method, command)
 	if installResult.Success {
 		ui.Print("    %s Reinstalled", ui.Green(ui.SymbolSuccess))
 	} else {
@@ -71,7 +84,7 @@ func runRepair(cmd *cobra.Command, args []string) {
 
 	// Step 3: Verify
 	ui.Print("  Step 3: Verifying...")
-	version, err := mgr.GetInstalledVersion(tool)
+	version, err := mgr.GetInstalledVersion(ctx, tool)
 	if err != nil {
 		ui.Error("Repair completed but verification failed: %v", err)
 		return
@@ -80,12 +93,12 @@ func runRepair(cmd *cobra.Command, args []string) {
 	ui.Success("Repair complete. %s v%s is now installed.", tool.Name, version)
 }
 
-func getInstalledToolKeys() []string {
-	mgr := manager.NewManager()
+func getInstalledToolKeys(ctx context.Context) []string {
+	mgr := newManager()
 	tools := config.GetAllTools()
 	var keys []string
 	for _, t := range tools {
-		if _, err := mgr.GetInstalledVersion(&t); err == nil {
+		if _, err := mgr.GetInstalledVersion(ctx, &t); err == nil {
 			keys = append(keys, t.Key)
 		}
 	}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/manager"
+)
+
+// OperationOutput represents the JSON output of an install or update of a tool
+type OperationOutput struct {
+	Key        string   `json:"key"`
+	Name       string   `json:"name"`
+	Success    bool     `json:"success"`
+	Method     string   `json:"method,omitempty"`
+	OldVersion string   `json:"old_version,omitempty"`
+	NewVersion string   `json:"new_version,omitempty"`
+	UpToDate   bool     `json:"up_to_date,omitempty"`
+	Output     string   `json:"output,omitempty"`
+	Log        string   `json:"log,omitempty"`
+	Purged     []string `json:"purged,omitempty"` // configuration deleted by uninstall --purge
+	Error      string   `json:"error,omitempty"`
+}
+
+func installOutput(key string, r *manager.InstallResult) OperationOutput {
+	out := OperationOutput{
+		Key:     key,
+		Name:    toolName(key),
+		Success: r.Success,
+		Method:  r.Method,
+		Output:  r.Output,
+		Log:     r.Log,
+	}
+	if r.Error != nil {
+		out.Error = r.Error.Error()
+	}
+	return out
+}
+
+func updateOutput(key string, r *manager.UpdateResult) OperationOutput {
+	out := OperationOutput{
+		Key:        key,
+		Name:       toolName(key),
+		Success:    r.Success,
+		Method:     r.Method,
+		OldVersion: r.OldVersion,
+		NewVersion: r.NewVersion,
+		UpToDate:   r.WasUpToDate,
+		Output:     r.Output,
+		Log:        r.Log,
+	}
+	if r.Error != nil {
+		out.Error = r.Error.Error()
+	}
+	return out
+}
+
+func toolName(key string) string {
+	if tool, ok := config.GetTool(key); ok {
+		return tool.Name
+	}
+	return key
+}
+
+// printOperationsJSON prints operation results sorted by tool key
+func printOperationsJSON(outputs []OperationOutput) {
+	sort.Slice(outputs, func(i, j int) bool {
+		return outputs[i].Key < outputs[j].Key
+	})
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(outputs)
+}
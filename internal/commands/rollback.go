@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/manager"
+	"github.com/jschneider/agenthelper/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var historyLast int
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <tool>",
+	Short: "Go back to the version a tool had before its last change",
+	Long: `Reinstall the version a tool had before the install or update that brought it
+to its current version, using the same install method. Running rollback again keeps
+going back through the recorded history.
+
+Examples:
+  agenthelper rollback claude-code`,
+	Args: cobra.ExactArgs(1),
+	Run:  runRollback,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return getInstalledToolKeys(cmd.Context()), cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history [tool]",
+	Short: "Show recorded installs and updates",
+	Long: `Show the recorded history of installs, updates and rollbacks with the versions
+they changed a tool from and to.
+
+Examples:
+  agenthelper history
+  agenthelper history claude-code --last 5`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runHistory,
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+	rootCmd.AddCommand(historyCmd)
+	rollbackCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, yesUsage)
+	historyCmd.Flags().IntVarP(&historyLast, "last", "n", 20, "number of entries to show (0 for all)")
+}
+
+func runRollback(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	toolKey := strings.ToLower(args[0])
+	mgr := newManager()
+
+	tool, ok := config.GetTool(toolKey)
+	if !ok {
+		ui.Error("Unknown tool: %s", toolKey)
+		return
+	}
+
+	result := mgr.Rollback(ctx, tool)
+
+	if viper.GetBool("json") {
+		printOperationsJSON([]OperationOutput{updateOutput(tool.Key, result)})
+		return
+	}
+
+	if result.Success {
+		ui.Success(result.Output)
+	} else {
+		ui.Error("Rollback failed: %v", result.Error)
+	}
+}
+
+func runHistory(cmd *cobra.Command, args []string) {
+	toolKey := ""
+	if len(args) > 0 {
+		toolKey = strings.ToLower(args[0])
+	}
+
+	entries, err := manager.ReadHistory(toolKey)
+	if err != nil {
+		ui.Error("Could not read history: %v", err)
+		os.Exit(1)
+	}
+	if historyLast > 0 && len(entries) > historyLast {
+		entries = entries[len(entries)-historyLast:]
+	}
+
+	if viper.GetBool("json") {
+		if entries == nil {
+			entries = []manager.HistoryEntry{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(entries)
+		return
+	}
+
+	if len(entries) == 0 {
+		ui.Info("No history recorded yet")
+		return
+	}
+
+	table := ui.NewTable([]string{"Time", "Tool", "Operation", "Change", "Method", ""})
+	for _, e := range entries {
+		from, to := e.FromVersion, e.ToVersion
+		if from == "" {
+			from = "-"
+		}
+		if to == "" {
+			to = "-"
+		}
+		status := ui.Green(ui.SymbolSuccess)
+		if !e.Success {
+			status = ui.Red(ui.SymbolError)
+		}
+		table.AddRow([]string{
+			e.Time.Local().Format("2006-01-02 15:04"),
+			e.Tool,
+			e.Operation,
+			fmt.Sprintf("%s → %s", from, to),
+			e.Method,
+			status,
+		})
+	}
+	table.Render()
+}
@@ -1,11 +1,14 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 
 	"github.com/fatih/color"
 	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/manager"
 	"github.com/jschneider/agenthelper/internal/ui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -16,6 +19,12 @@ var (
 	cfgFile    string
 	jsonOutput bool
 	noColor    bool
+	refresh    bool
+	jobs       int
+	quiet      bool
+
+	// managerOptions are the settings from flags and the config file, set in initConfig
+	managerOptions = manager.DefaultOptions()
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -42,8 +51,21 @@ Run without arguments for interactive mode, or use subcommands:
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// Ctrl+C cancels the command's context so running operations stop cleanly; a second
+// Ctrl+C terminates immediately.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return rootCmd.ExecuteContext(ctx)
+}
+
+// newManager creates a tool manager with the settings from flags and the config file
+func newManager() *manager.Manager {
+	return manager.NewManager(managerOptions)
 }
 
 // SetVersion sets the version string from main
@@ -63,10 +85,20 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.agenthelper.yaml)")
 	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "output in JSON format")
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output")
+	rootCmd.PersistentFlags().BoolVar(&refresh, "refresh", false, "bypass cached latest-version lookups")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "don't show the output of install and update commands while they run")
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", manager.DefaultJobs, "number of tools to install or update at the same time")
+	rootCmd.PersistentFlags().Bool("offline", false, "don't use the network: latest versions come from the cache, installs from the mirror")
+	rootCmd.PersistentFlags().String("mirror", "", "directory of pre-downloaded packages to install from when offline")
 
 	// Bind flags to viper
 	viper.BindPFlag("json", rootCmd.PersistentFlags().Lookup("json"))
 	viper.BindPFlag("no-color", rootCmd.PersistentFlags().Lookup("no-color"))
+	viper.BindPFlag("jobs", rootCmd.PersistentFlags().Lookup("jobs"))
+	viper.BindPFlag("offline", rootCmd.PersistentFlags().Lookup("offline"))
+	viper.BindPFlag("mirror", rootCmd.PersistentFlags().Lookup("mirror"))
+
+	viper.SetDefault("cache.ttl", manager.DefaultCacheTTL)
 }
 
 func initConfig() {
@@ -98,10 +130,36 @@ func initConfig() {
 		}
 	}
 
+	sources, err := manager.VersionSourceURLs(viper.GetStringMapString("version_sources"))
+	if err != nil && !jsonOutput {
+		ui.Warn("Invalid version_sources in config: %v", err)
+	}
+	managerOptions = manager.Options{
+		VersionSources: sources,
+		// Latest-version lookups are cached on disk
+		CacheTTL: viper.GetDuration("cache.ttl"),
+		Refresh:  refresh,
+		// Without --offline the network is probed the first time it is needed
+		Offline: manager.OfflineDetector(viper.GetBool("offline"), sources),
+		Mirror:  viper.GetString("mirror"),
+		// Downloads are shown for confirmation unless --yes is given or nobody can answer
+		AssumeYes:    assumeYes,
+		Interactive:  !jsonOutput && ui.IsTerminal(os.Stdin),
+		VerifiedOnly: viper.GetBool("require_verified_downloads"),
+		// Command output is streamed unless it would mix with JSON or isn't watched
+		StreamOutput: !jsonOutput && !quiet && ui.IsTerminal(os.Stdout),
+		Jobs:         viper.GetInt("jobs"),
+	}
+	config.CatalogOffline = managerOptions.Offline
+	ui.SetQuietMode(jsonOutput)
+
 	// Load tool definitions
-	if err := config.LoadToolDefinitions(); err != nil {
+	if err := config.LoadToolDefinitions(cfgFile); err != nil {
 		if !jsonOutput {
 			ui.Warn("Could not load tool definitions: %v", err)
 		}
 	}
+	if config.CatalogError != nil && !jsonOutput {
+		ui.Warn("Could not load tool catalog: %v", config.CatalogError)
+	}
 }
@@ -7,7 +7,6 @@ import (
 	"strings"
 
 	"github.com/jschneider/agenthelper/internal/config"
-	"github.com/jschneider/agenthelper/internal/manager"
 	"github.com/jschneider/agenthelper/internal/platform"
 	"github.com/jschneider/agenthelper/internal/ui"
 	"github.com/spf13/cobra"
@@ -31,7 +30,7 @@ Examples:
 		if len(args) != 0 {
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		}
-		return getInstalledToolKeys(), cobra.ShellCompDirectiveNoFileComp
+		return getInstalledToolKeys(cmd.Context()), cobra.ShellCompDirectiveNoFileComp
 	},
 }
 
@@ -40,6 +39,7 @@ func init() {
 }
 
 func runTool(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
 	toolKey := strings.ToLower(args[0])
 	toolArgs := args[1:]
 
@@ -53,10 +53,10 @@ func runTool(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	mgr := manager.NewManager()
+	mgr := newManager()
 
 	// Check if installed
-	if _, err := mgr.GetInstalledVersion(tool); err != nil {
+	if _, err := mgr.GetInstalledVersion(ctx, tool); err != nil {
 		ui.Error("%s is not installed", tool.Name)
 		fmt.Println("Use 'agenthelper install' to install it first.")
 		os.Exit(1)
@@ -33,6 +33,9 @@ type ToolStatusOutput struct {
 	Installed      bool     `json:"installed"`
 	InstalledVer   string   `json:"installed_version,omitempty"`
 	LatestVer      string   `json:"latest_version,omitempty"`
+	TargetVer      string   `json:"target_version,omitempty"`
+	InstallMethod  string   `json:"install_method,omitempty"`
+	Constraint     string   `json:"version_constraint,omitempty"`
 	HasUpdate      bool     `json:"has_update"`
 	InstallMethods []string `json:"install_methods,omitempty"`
 	Command        string   `json:"command"`
@@ -43,7 +46,8 @@ func init() {
 }
 
 func runStatus(cmd *cobra.Command, args []string) {
-	mgr := manager.NewManager()
+	ctx := cmd.Context()
+	mgr := newManager()
 	plat := platform.Current()
 
 	if !viper.GetBool("json") {
@@ -58,7 +62,7 @@ func runStatus(cmd *cobra.Command, args []string) {
 		defer spinner.Stop()
 	}
 
-	statuses := mgr.GetAllToolStatus()
+	statuses := mgr.GetAllToolStatus(ctx)
 
 	if viper.GetBool("json") {
 		outputJSON(plat, statuses)
@@ -84,6 +88,9 @@ func outputJSON(plat *platform.Platform, statuses []*manager.ToolStatus) {
 			Installed:      s.IsInstalled,
 			InstalledVer:   s.InstalledVer,
 			LatestVer:      s.LatestVer,
+			TargetVer:      s.TargetVer,
+			InstallMethod:  s.Method,
+			Constraint:     s.Tool.Version,
 			HasUpdate:      s.HasUpdate,
 			InstallMethods: s.InstallMethods,
 			Command:        s.Tool.Command,
@@ -101,21 +108,30 @@ func displayStatusTable(statuses []*manager.ToolStatus) {
 	for _, s := range statuses {
 		status := getStatusSymbol(s)
 		installed := "-"
+		via := "-"
 		latest := "-"
 		command := s.Tool.Command
 
 		if s.IsInstalled {
 			installed = s.InstalledVer
+			via = s.Method
+			if via == "" {
+				via = "unknown"
+			}
 		}
 
 		if s.LatestVer != "" {
 			latest = s.LatestVer
 		}
+		if s.Tool.Version != "" {
+			latest = formatConstrainedVersion(s)
+		}
 
 		table.AddRow([]string{
 			s.Tool.Name,
 			status,
 			installed,
+			via,
 			latest,
 			command,
 		})
@@ -141,3 +157,12 @@ func getStatusSymbol(s *manager.ToolStatus) string {
 	}
 	return ui.Green(ui.SymbolSuccess + " OK")
 }
+
+// formatConstrainedVersion shows the highest allowed version next to the tool's constraint
+func formatConstrainedVersion(s *manager.ToolStatus) string {
+	target := s.TargetVer
+	if target == "" {
+		target = "-"
+	}
+	return fmt.Sprintf("%s (%s)", target, s.Tool.Version)
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/manager"
+	"github.com/jschneider/agenthelper/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var syncLocked bool
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Install tools pinned in the lockfile",
+	Long: `Install every tool recorded in the lockfile that is missing on this machine,
+using the pinned version and install method.
+
+With --locked, tools that are already installed are also upgraded or downgraded
+to exactly the pinned version.
+
+Examples:
+  agenthelper sync
+  agenthelper sync --locked
+  agenthelper sync --locked --file team/agenthelper.lock`,
+	Args: cobra.NoArgs,
+	Run:  runSync,
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().BoolVar(&syncLocked, "locked", false, "install, upgrade or downgrade every tool to exactly the pinned version")
+	syncCmd.Flags().StringVarP(&lockFile, "file", "f", manager.LockfileName, "lockfile path")
+	syncCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, yesUsage)
+}
+
+func runSync(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	lock, err := manager.ReadLockfile(lockFile)
+	if err != nil {
+		ui.Error("%v", err)
+		fmt.Println("Run 'agenthelper lock' to create one.")
+		os.Exit(1)
+	}
+
+	mgr := newManager()
+	osKey := mgr.GetPlatform().GetOSKey()
+
+	changedCount := 0
+	okCount := 0
+	skipCount := 0
+	failCount := 0
+
+	for _, entry := range lock.Tools {
+		if entry.Version == "" {
+			continue
+		}
+
+		tool, ok := config.GetTool(entry.Key)
+		if !ok {
+			failCount++
+			ui.Print("  %s %s: not defined in tool configuration", ui.Red(ui.SymbolError), entry.Key)
+			continue
+		}
+
+		installed, err := mgr.GetInstalledVersion(ctx, tool)
+		if err == nil && (!syncLocked || manager.SameVersion(installed, entry.Version)) {
+			okCount++
+			ui.Print("  %s %s: v%s", ui.Green(ui.SymbolSuccess), tool.Name, installed)
+			continue
+		}
+
+		// A method recorded on another OS is not used here. The pinned version is installed
+		// with the best method of this machine, or skipped when that method cannot pin it.
+		method := entry.Method
+		if entry.Platform != "" && entry.Platform != osKey {
+			plan := mgr.PlanInstall(ctx, tool, "", entry.Version)
+			if plan.Error != "" {
+				skipCount++
+				ui.Print("  %s %s: locked on %s, skipped: %s", ui.Yellow(ui.SymbolWarn), tool.Name, entry.Platform, plan.Error)
+				continue
+			}
+			method = plan.Method
+		}
+
+		result := mgr.InstallVersion(ctx, tool, method, entry.Version)
+		if !result.Success {
+			failCount++
+			ui.Print("  %s %s: %v", ui.Red(ui.SymbolError), tool.Name, result.Error)
+			continue
+		}
+
+		changedCount++
+		if installed == "" {
+			ui.Print("  %s %s: installed v%s", ui.Green(ui.SymbolSuccess), tool.Name, entry.Version)
+		} else {
+			ui.Print("  %s %s: v%s → v%s", ui.Green(ui.SymbolSuccess), tool.Name, installed, entry.Version)
+		}
+	}
+
+	fmt.Println()
+	ui.Info("Summary: %d changed, %d already in sync, %d skipped, %d failed", changedCount, okCount, skipCount, failCount)
+
+	if failCount > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/manager"
+	"github.com/jschneider/agenthelper/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	uninstallPurge bool
+)
+
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall <tool>",
+	Short: "Uninstall a tool",
+	Long: `Uninstall a tool with its uninstall spec, or with the package manager it is
+installed with when the tool defines no uninstall spec.
+
+Use --purge to also delete the tool's configuration directories, e.g. ~/.claude.
+
+Examples:
+  agenthelper uninstall aider
+  agenthelper uninstall claude-code --purge
+  agenthelper uninstall claude-code --dry-run`,
+	Args: cobra.ExactArgs(1),
+	Run:  runUninstall,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return getInstalledToolKeys(cmd.Context()), cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(uninstallCmd)
+	uninstallCmd.Flags().BoolVar(&uninstallPurge, "purge", false, "also delete the tool's configuration directories")
+	uninstallCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "don't ask before purging configuration or running verified downloads")
+	uninstallCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be uninstalled without changing anything")
+}
+
+func runUninstall(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	toolKey := strings.ToLower(args[0])
+	mgr := newManager()
+
+	tool, ok := config.GetTool(toolKey)
+	if !ok {
+		ui.Error("Unknown tool: %s", toolKey)
+		fmt.Println("\nAvailable tools:")
+		for _, t := range config.GetAllTools() {
+			fmt.Printf("  - %s (%s)\n", t.Key, t.Name)
+		}
+		return
+	}
+
+	if dryRun {
+		plan := mgr.PlanUninstall(ctx, tool)
+		if uninstallPurge {
+			plan.Purge = manager.ConfigPaths(tool)
+		}
+		printPlans([]*manager.Plan{plan})
+		return
+	}
+
+	// Nobody can be asked in JSON mode, so deleting configuration has to be confirmed upfront
+	if viper.GetBool("json") && uninstallPurge && !assumeYes {
+		printOperationsJSON([]OperationOutput{{Key: tool.Key, Name: tool.Name, Error: "--purge with --json requires --yes"}})
+		os.Exit(1)
+	}
+
+	if _, err := mgr.GetInstalledVersion(ctx, tool); err != nil {
+		if viper.GetBool("json") {
+			printOperationsJSON([]OperationOutput{{Key: tool.Key, Name: tool.Name, Error: tool.Name + " is not installed"}})
+		} else {
+			ui.Error("%s is not installed", tool.Name)
+		}
+		os.Exit(1)
+	}
+	warnDependents(ctx, mgr, tool)
+
+	result := mgr.Uninstall(ctx, tool)
+
+	if viper.GetBool("json") {
+		out := installOutput(tool.Key, result)
+		if result.Success && uninstallPurge {
+			removed, err := manager.PurgeConfig(tool)
+			out.Purged = removed
+			if err != nil {
+				out.Success = false
+				out.Error = fmt.Sprintf("could not purge configuration: %v", err)
+			}
+		}
+		printOperationsJSON([]OperationOutput{out})
+		if !out.Success {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !result.Success {
+		ui.Error("Uninstall failed: %v", result.Error)
+		os.Exit(1)
+	}
+	ui.Success(result.Output)
+	if uninstallPurge {
+		purgeConfig(tool, assumeYes)
+	}
+}
+
+// warnDependents warns about installed tools that need the tool being uninstalled
+func warnDependents(ctx context.Context, mgr *manager.Manager, tool *config.ToolDefinition) {
+	for _, t := range config.GetAllTools() {
+		for _, dep := range t.DependsOn {
+			if dep != tool.Key {
+				continue
+			}
+			if _, err := mgr.GetInstalledVersion(ctx, &t); err == nil {
+				ui.Warn("%s depends on %s and will stop working", t.Name, tool.Name)
+			}
+		}
+	}
+}
+
+// purgeConfig deletes a tool's configuration after asking, unless confirmed is set
+func purgeConfig(tool *config.ToolDefinition, confirmed bool) {
+	paths := manager.ConfigPaths(tool)
+	if len(paths) == 0 {
+		return
+	}
+	if !confirmed {
+		ui.Print("This deletes:")
+		for _, p := range paths {
+			ui.Print("  %s", p)
+		}
+		if !ui.PromptConfirm(fmt.Sprintf("Delete the configuration of %s?", tool.Name)) {
+			return
+		}
+	}
+
+	removed, err := manager.PurgeConfig(tool)
+	for _, p := range removed {
+		ui.Print("  %s Deleted %s", ui.Green(ui.SymbolSuccess), p)
+	}
+	if err != nil {
+		ui.Error("Could not purge configuration: %v", err)
+	}
+}
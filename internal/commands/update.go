@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -8,16 +9,21 @@ import (
 	"github.com/jschneider/agenthelper/internal/manager"
 	"github.com/jschneider/agenthelper/internal/ui"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var updateCmd = &cobra.Command{
-	Use:   "update [tool|all]",
+	Use:   "update [tool[@version]|all]",
 	Short: "Update installed tools",
 	Long: `Update one or all installed coding tools to their latest versions.
 
+Append @<version> to move a tool to a specific version, including older ones.
+
 Examples:
   agenthelper update claude-code
+  agenthelper update claude-code@1.0.30
   agenthelper update all
+  agenthelper update all --dry-run
   agenthelper update  # same as 'update all'`,
 	Args: cobra.MaximumNArgs(1),
 	Run:  runUpdate,
@@ -31,18 +37,22 @@ Examples:
 
 func init() {
 	rootCmd.AddCommand(updateCmd)
+	updateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be updated without changing anything")
+	updateCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, yesUsage)
 }
 
 func runUpdate(cmd *cobra.Command, args []string) {
-	mgr := manager.NewManager()
+	ctx := cmd.Context()
+	mgr := newManager()
 
 	toolKey := "all"
+	toolVersion := ""
 	if len(args) > 0 {
-		toolKey = strings.ToLower(args[0])
+		toolKey, toolVersion = manager.ParseToolVersion(strings.ToLower(args[0]))
 	}
 
 	if toolKey == "all" {
-		runUpdateAll(mgr)
+		runUpdateAll(ctx, mgr)
 		return
 	}
 
@@ -56,14 +66,29 @@ func runUpdate(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if dryRun {
+		printPlans([]*manager.Plan{mgr.PlanUpdate(ctx, tool, toolVersion)})
+		return
+	}
+
 	// Check if installed
-	if _, err := mgr.GetInstalledVersion(tool); err != nil {
+	if _, err := mgr.GetInstalledVersion(ctx, tool); err != nil {
 		ui.Error("%s is not installed", tool.Name)
 		fmt.Println("Use 'agenthelper install' to install it first.")
 		return
 	}
 
-	result := mgr.Update(tool)
+	var result *manager.UpdateResult
+	if toolVersion != "" {
+		result = mgr.UpdateToVersion(ctx, tool, toolVersion)
+	} else {
+		result = mgr.Update(ctx, tool)
+	}
+
+	if viper.GetBool("json") {
+		printOperationsJSON([]OperationOutput{updateOutput(tool.Key, result)})
+		return
+	}
 
 	if result.Success {
 		if result.WasUpToDate {
@@ -76,9 +101,23 @@ func runUpdate(cmd *cobra.Command, args []string) {
 	}
 }
 
-func runUpdateAll(mgr *manager.Manager) {
+func runUpdateAll(ctx context.Context, mgr *manager.Manager) {
+	if dryRun {
+		printPlans(mgr.PlanUpdateAll(ctx))
+		return
+	}
+
 	ui.Info("Updating all installed tools...")
-	results := mgr.UpdateAll()
+	results := mgr.UpdateAll(ctx)
+
+	if viper.GetBool("json") {
+		var outputs []OperationOutput
+		for key, result := range results {
+			outputs = append(outputs, updateOutput(key, result))
+		}
+		printOperationsJSON(outputs)
+		return
+	}
 
 	updatedCount := 0
 	upToDateCount := 0
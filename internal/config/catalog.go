@@ -0,0 +1,233 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jschneider/agenthelper/internal/platform"
+	"github.com/spf13/viper"
+)
+
+// catalogFileName is the file looked up when the catalog location is a directory
+const catalogFileName = "tools.yaml"
+
+// CatalogSettings configures an additional tool catalog, read from the "catalog" section
+// of the application config file
+type CatalogSettings struct {
+	URL          string        `mapstructure:"url"`           // https://, file:// or a local file or directory
+	SignatureURL string        `mapstructure:"signature_url"` // defaults to URL + ".sig"
+	PublicKey    string        `mapstructure:"public_key"`    // base64 encoded ed25519 public key
+	Refresh      time.Duration `mapstructure:"refresh"`       // how long a cached catalog is used without refetching
+}
+
+// CatalogError holds the error of the last catalog load, if any. A broken catalog never
+// prevents the other layers from loading.
+var CatalogError error
+
+var catalogClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// CatalogOffline reports whether the network is unavailable, in which case a remote
+// catalog is not fetched and the cached copy is used whatever its age
+var CatalogOffline = func() bool { return false }
+
+// GetCatalogSettings returns the configured catalog settings
+func GetCatalogSettings() CatalogSettings {
+	settings := CatalogSettings{Refresh: 24 * time.Hour}
+	_ = viper.UnmarshalKey("catalog", &settings)
+	if settings.SignatureURL == "" && settings.URL != "" {
+		settings.SignatureURL = catalogPath(settings.URL) + ".sig"
+	}
+	return settings
+}
+
+// loadCatalogLayer returns the verified catalog, or nil if no catalog is configured.
+// A fresh cached copy is used when available; a failed fetch falls back to the cache.
+func loadCatalogLayer(settings CatalogSettings, forceRefresh bool) (*Layer, error) {
+	if settings.URL == "" {
+		return nil, nil
+	}
+	if settings.PublicKey == "" {
+		return nil, fmt.Errorf("catalog.public_key is required to verify %s", settings.URL)
+	}
+	publicKey, err := decodePublicKey(settings.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cachePath, err := catalogCachePath(settings.URL)
+	if err != nil {
+		return nil, err
+	}
+	layer := &Layer{Name: LayerCatalog, Path: settings.URL}
+
+	if !forceRefresh {
+		if info, err := os.Stat(cachePath); err == nil && time.Since(info.ModTime()) < settings.Refresh {
+			if data, err := readVerified(cachePath, cachePath+".sig", publicKey); err == nil {
+				layer.data = data
+				return layer, nil
+			}
+		}
+	}
+
+	if !forceRefresh && isRemote(settings.URL) && CatalogOffline() {
+		data, err := readVerified(cachePath, cachePath+".sig", publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("offline and no cached copy of catalog %s", settings.URL)
+		}
+		layer.data = data
+		return layer, nil
+	}
+
+	data, signature, fetchErr := fetchCatalog(settings)
+	if fetchErr == nil {
+		if err := VerifyCatalog(data, signature, publicKey); err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", settings.URL, err)
+		}
+		if err := writeCatalogCache(cachePath, data, signature); err != nil {
+			return nil, err
+		}
+		layer.data = data
+		return layer, nil
+	}
+
+	// Fall back to the last verified copy when the catalog cannot be reached
+	data, err = readVerified(cachePath, cachePath+".sig", publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch catalog: %w", fetchErr)
+	}
+	layer.data = data
+	return layer, nil
+}
+
+// RefreshCatalog fetches and verifies the configured catalog, bypassing the cache
+func RefreshCatalog() error {
+	layer, err := loadCatalogLayer(GetCatalogSettings(), true)
+	if err != nil {
+		return err
+	}
+	if layer == nil {
+		return fmt.Errorf("no catalog configured")
+	}
+	return nil
+}
+
+// VerifyCatalog checks a detached base64 encoded ed25519 signature of a catalog
+func VerifyCatalog(data, signature []byte, publicKey ed25519.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if !ed25519.Verify(publicKey, data, sig) {
+		return fmt.Errorf("signature verification failed")
+	}
+	return nil
+}
+
+// SignCatalog creates a detached base64 encoded ed25519 signature of a catalog
+func SignCatalog(data []byte, privateKey ed25519.PrivateKey) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, data)) + "\n")
+}
+
+func decodePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("catalog.public_key must be a base64 encoded ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// fetchCatalog reads the catalog and its signature from a URL or local path
+func fetchCatalog(settings CatalogSettings) ([]byte, []byte, error) {
+	data, err := readLocation(catalogPath(settings.URL))
+	if err != nil {
+		return nil, nil, err
+	}
+	signature, err := readLocation(settings.SignatureURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch catalog signature: %w", err)
+	}
+	return data, signature, nil
+}
+
+// catalogPath resolves a directory location to the catalog file inside it
+func catalogPath(location string) string {
+	local := strings.TrimPrefix(location, "file://")
+	if info, err := os.Stat(local); err == nil && info.IsDir() {
+		return filepath.Join(local, catalogFileName)
+	}
+	return location
+}
+
+// isRemote reports whether a location is fetched over HTTP
+func isRemote(location string) bool {
+	u, err := url.Parse(location)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+func readLocation(location string) ([]byte, error) {
+	if isRemote(location) {
+		resp, err := catalogClient.Get(location)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s returned status %d", location, resp.StatusCode)
+		}
+		return io.ReadAll(resp.Body)
+	}
+
+	return os.ReadFile(strings.TrimPrefix(location, "file://"))
+}
+
+func readVerified(path, signaturePath string, publicKey ed25519.PublicKey) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := os.ReadFile(signaturePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyCatalog(data, signature, publicKey); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// catalogCachePath returns where the catalog at a location is cached. Each location has
+// its own copy, so a changed catalog.url never serves the catalog of the previous one.
+func catalogCachePath(location string) (string, error) {
+	paths, err := platform.GetPaths()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(location))
+	return filepath.Join(paths.CacheDir, "catalog", hex.EncodeToString(sum[:8]), catalogFileName), nil
+}
+
+func writeCatalogCache(path string, data, signature []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create catalog cache: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to cache catalog: %w", err)
+	}
+	if err := os.WriteFile(path+".sig", signature, 0644); err != nil {
+		return fmt.Errorf("failed to cache catalog signature: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// catalogServer serves signed catalogs by path and counts the requests for them
+type catalogServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	files    map[string][]byte
+	requests int
+	down     bool
+}
+
+func newCatalogServer(t *testing.T) *catalogServer {
+	t.Helper()
+	s := &catalogServer{files: make(map[string][]byte)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		data, ok := s.files[r.URL.Path]
+		switch {
+		case s.down:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case !ok:
+			http.NotFound(w, r)
+		default:
+			w.Write(data)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// publish serves a catalog and its signature at path and path.sig
+func (s *catalogServer) publish(path string, data, signature []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[path] = data
+	s.files[path+".sig"] = signature
+}
+
+func (s *catalogServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// newCatalogKey returns a signing key, and catalog settings with its public key for the
+// catalog at url. The catalog cache goes to a temporary home directory.
+func newCatalogKey(t *testing.T, url string) (ed25519.PrivateKey, CatalogSettings) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return private, CatalogSettings{
+		URL:          url,
+		SignatureURL: url + ".sig",
+		PublicKey:    base64.StdEncoding.EncodeToString(public),
+		Refresh:      time.Hour,
+	}
+}
+
+func TestVerifyCatalog(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPublic, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("tools: []\n")
+	signature := SignCatalog(data, private)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		signature []byte
+		key       ed25519.PublicKey
+		wantErr   string
+	}{
+		{"valid", data, signature, public, ""},
+		{"tampered catalog", []byte("tools: [{key: evil}]\n"), signature, public, "verification failed"},
+		{"other key", data, signature, otherPublic, "verification failed"},
+		{"truncated signature", data, signature[:20], public, "verification failed"},
+		{"not base64", data, []byte("not a signature!"), public, "invalid signature encoding"},
+		{"empty signature", data, nil, public, "verification failed"},
+	}
+	for _, tt := range tests {
+		err := VerifyCatalog(tt.data, tt.signature, tt.key)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: VerifyCatalog() = %v, want nil", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: VerifyCatalog() = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadCatalogLayer(t *testing.T) {
+	server := newCatalogServer(t)
+	key, settings := newCatalogKey(t, server.URL+"/a/tools.yaml")
+	first := []byte("tools: [{key: first}]\n")
+	server.publish("/a/tools.yaml", first, SignCatalog(first, key))
+
+	load := func(settings CatalogSettings) string {
+		t.Helper()
+		layer, err := loadCatalogLayer(settings, false)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		return string(layer.data)
+	}
+
+	// Fetched and cached, then served from the cache while it is fresh
+	if got := load(settings); got != string(first) {
+		t.Fatalf("first load = %q, want the published catalog", got)
+	}
+	if got := load(settings); got != string(first) || server.requestCount() != 2 {
+		t.Errorf("second load = %q after %d requests, want the cached catalog without a request", got, server.requestCount())
+	}
+
+	// A stale cache is refetched, and the cache is used when the server is down
+	settings.Refresh = 0
+	server.mu.Lock()
+	server.down = true
+	server.mu.Unlock()
+	if got := load(settings); got != string(first) {
+		t.Errorf("load with the server down = %q, want the cached catalog", got)
+	}
+	server.mu.Lock()
+	server.down = false
+	server.mu.Unlock()
+
+	// A catalog that does not verify is refused and does not replace the cache
+	evil := []byte("tools: [{key: evil}]\n")
+	server.publish("/a/tools.yaml", evil, SignCatalog(first, key))
+	if got := load(settings); !strings.Contains(got, "signature verification failed") {
+		t.Errorf("load of a tampered catalog = %q, want a verification error", got)
+	}
+	settings.Refresh = time.Hour
+	if got := load(settings); got != string(first) {
+		t.Errorf("load after the tampered catalog = %q, want the cached catalog", got)
+	}
+
+	// Another URL does not get the cached catalog of the previous one
+	second := []byte("tools: [{key: second}]\n")
+	server.publish("/b/tools.yaml", second, SignCatalog(second, key))
+	moved := settings
+	moved.URL, moved.SignatureURL = server.URL+"/b/tools.yaml", server.URL+"/b/tools.yaml.sig"
+	if got := load(moved); got != string(second) {
+		t.Errorf("load after changing the URL = %q, want the catalog at the new URL", got)
+	}
+	if got := load(settings); got != string(first) {
+		t.Errorf("load of the first URL = %q, want its own cached catalog", got)
+	}
+}
+
+func TestLoadCatalogLayerOffline(t *testing.T) {
+	server := newCatalogServer(t)
+	key, settings := newCatalogKey(t, server.URL+"/tools.yaml")
+	data := []byte("tools: [{key: tool}]\n")
+	server.publish("/tools.yaml", data, SignCatalog(data, key))
+
+	offline := false
+	CatalogOffline = func() bool { return offline }
+	t.Cleanup(func() { CatalogOffline = func() bool { return false } })
+
+	// Offline without a cached copy
+	offline = true
+	if _, err := loadCatalogLayer(settings, false); err == nil || !strings.Contains(err.Error(), "offline and no cached copy") {
+		t.Errorf("loadCatalogLayer() offline without a cache error = %v", err)
+	}
+	if n := server.requestCount(); n != 0 {
+		t.Errorf("%d requests while offline, want none", n)
+	}
+
+	offline = false
+	if _, err := loadCatalogLayer(settings, false); err != nil {
+		t.Fatalf("loadCatalogLayer() failed: %v", err)
+	}
+
+	// Offline the cached copy is used whatever its age, without a request
+	offline = true
+	settings.Refresh = 0
+	requests := server.requestCount()
+	layer, err := loadCatalogLayer(settings, false)
+	if err != nil || string(layer.data) != string(data) {
+		t.Errorf("loadCatalogLayer() offline = %v, want the cached catalog", err)
+	}
+	if n := server.requestCount(); n != requests {
+		t.Errorf("%d requests while offline, want none", n-requests)
+	}
+}
+
+func TestCatalogCachePath(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	a, errA := catalogCachePath("https://example.com/a/tools.yaml")
+	b, errB := catalogCachePath("https://example.com/b/tools.yaml")
+	again, _ := catalogCachePath("https://example.com/a/tools.yaml")
+	if errA != nil || errB != nil {
+		t.Fatalf("catalogCachePath() failed: %v, %v", errA, errB)
+	}
+	if a == b || a != again {
+		t.Errorf("catalogCachePath() = %s, %s, %s; want one path per URL", a, b, again)
+	}
+}
@@ -2,12 +2,10 @@ package config
 
 import (
 	"embed"
-	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
-	"gopkg.in/yaml.v3"
 )
 
 //go:embed embedded_tools.yaml
@@ -27,22 +25,84 @@ type ToolDefinition struct {
 	VersionCmd     string                 `yaml:"version_cmd" mapstructure:"version_cmd"`
 	VersionPattern string                 `yaml:"version_pattern" mapstructure:"version_pattern"`
 	VersionSource  VersionSource          `yaml:"version_source" mapstructure:"version_source"`
+	Version        string                 `yaml:"version,omitempty" mapstructure:"version"` // semver constraint, e.g. "~1.2" or ">=0.40 <0.50"
 	Install        map[string]InstallSpec `yaml:"install" mapstructure:"install"`
 	Uninstall      map[string]InstallSpec `yaml:"uninstall,omitempty" mapstructure:"uninstall"`
 	EnvVars        []string               `yaml:"env_vars,omitempty" mapstructure:"env_vars"`
 	Description    string                 `yaml:"description,omitempty" mapstructure:"description"`
+	Disabled       bool                   `yaml:"disabled,omitempty" mapstructure:"disabled"` // drops a tool defined by a lower layer
+	Timeout        ToolTimeouts           `yaml:"timeout,omitempty" mapstructure:"timeout"`
+	DependsOn      []string               `yaml:"depends_on,omitempty" mapstructure:"depends_on"`   // keys of tools that must be installed first
+	ConfigDirs     []string               `yaml:"config_dirs,omitempty" mapstructure:"config_dirs"` // removed by uninstall --purge, e.g. "~/.claude"
+	Mirror         MirrorSpec             `yaml:"mirror,omitempty" mapstructure:"mirror"`           // packages in the offline mirror directory
+}
+
+// MirrorSpec names the pre-downloaded packages of a tool in the mirror directory that
+// offline installs come from, as glob patterns such as "aider_chat-*.whl". The newest
+// matching file is installed.
+type MirrorSpec struct {
+	Npm      string `yaml:"npm,omitempty" mapstructure:"npm"`           // tarball from npm pack
+	Pip      string `yaml:"pip,omitempty" mapstructure:"pip"`           // wheel; dependencies are installed from the same directory
+	Deb      string `yaml:"deb,omitempty" mapstructure:"deb"`           // Debian package, installed with apt
+	AppImage string `yaml:"appimage,omitempty" mapstructure:"appimage"` // copied to the user bin directory
+}
+
+// Default timeouts of tool operations
+const (
+	DefaultVersionTimeout = 10 * time.Second
+	DefaultCheckTimeout   = 15 * time.Second
+	DefaultInstallTimeout = 15 * time.Minute
+)
+
+// ToolTimeouts limits how long operations on a tool may take, as durations like "30s" or "5m"
+type ToolTimeouts struct {
+	Version string `yaml:"version,omitempty" mapstructure:"version"` // running version_cmd
+	Check   string `yaml:"check,omitempty" mapstructure:"check"`     // looking up the latest version
+	Install string `yaml:"install,omitempty" mapstructure:"install"` // installing, updating and uninstalling
+}
+
+// VersionTimeout returns how long version_cmd may run
+func (t *ToolDefinition) VersionTimeout() time.Duration {
+	return parseTimeout(t.Timeout.Version, DefaultVersionTimeout)
+}
+
+// CheckTimeout returns how long a latest-version lookup may take
+func (t *ToolDefinition) CheckTimeout() time.Duration {
+	return parseTimeout(t.Timeout.Check, DefaultCheckTimeout)
+}
+
+// InstallTimeout returns how long an install, update or uninstall command may run
+func (t *ToolDefinition) InstallTimeout() time.Duration {
+	return parseTimeout(t.Timeout.Install, DefaultInstallTimeout)
+}
+
+// parseTimeout falls back to the default for empty or invalid values; config validate
+// reports the invalid ones
+func parseTimeout(value string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	return fallback
 }
 
 // VersionSource defines where to check for latest versions
 type VersionSource struct {
-	Type    string `yaml:"type" mapstructure:"type"` // npm, github, pypi, vscode-update, cursor-todesktop, unknown
-	Package string `yaml:"package,omitempty" mapstructure:"package"`
-	Owner   string `yaml:"owner,omitempty" mapstructure:"owner"`
-	Repo    string `yaml:"repo,omitempty" mapstructure:"repo"`
-	Channel string `yaml:"channel,omitempty" mapstructure:"channel"` // for vscode-update: stable, insider
+	Type       string `yaml:"type" mapstructure:"type"` // see VersionSourceTypes
+	Package    string `yaml:"package,omitempty" mapstructure:"package"`
+	Owner      string `yaml:"owner,omitempty" mapstructure:"owner"`
+	Repo       string `yaml:"repo,omitempty" mapstructure:"repo"`
+	Channel    string `yaml:"channel,omitempty" mapstructure:"channel"`       // for npm: the dist-tag to follow (default latest); for vscode-update: stable, insider
+	Prerelease bool   `yaml:"prerelease,omitempty" mapstructure:"prerelease"` // for github, github-tags, pypi and crates: include pre-releases
+	Registry   string `yaml:"registry,omitempty" mapstructure:"registry"`     // npm registry, PyPI index, GitHub, crates.io, Homebrew or VS Code update API URL; also used for npm and pip installs
+	Cask       bool   `yaml:"cask,omitempty" mapstructure:"cask"`             // for homebrew: look up a cask instead of a formula
+	URL        string `yaml:"url,omitempty" mapstructure:"url"`               // for http-json and http-regex
+	JSONPath   string `yaml:"json_path,omitempty" mapstructure:"json_path"`   // for http-json, e.g. $.releases[0].version
+	Pattern    string `yaml:"pattern,omitempty" mapstructure:"pattern"`       // for http-regex and command; the first group is the version
+	Command    string `yaml:"command,omitempty" mapstructure:"command"`       // for command: a shell command printing the latest version
 }
 
-// InstallSpec defines installation commands for different package managers
+// InstallSpec defines installation commands for different package managers.
+// Commands may contain a {{version}} placeholder that is replaced with the version to install.
 type InstallSpec struct {
 	WinGet string `yaml:"winget,omitempty" mapstructure:"winget"`
 	Npm    string `yaml:"npm,omitempty" mapstructure:"npm"`
@@ -51,6 +111,62 @@ type InstallSpec struct {
 	Pacman string `yaml:"pacman,omitempty" mapstructure:"pacman"`
 	Pip    string `yaml:"pip,omitempty" mapstructure:"pip"`
 	Script string `yaml:"script,omitempty" mapstructure:"script"`
+	// Download is installed from a file that is downloaded and verified first, instead of
+	// a script piped into a shell
+	Download DownloadSpec `yaml:"download,omitempty" mapstructure:"download"`
+}
+
+// FilePlaceholder is replaced in the run command of a download with the downloaded file
+const FilePlaceholder = "{{file}}"
+
+// DownloadSpec defines a download install. The URLs may contain a {{version}} placeholder.
+// The file is verified against sha256 or the checksum file, and the signature if one is
+// given, and shown for confirmation before run is executed.
+type DownloadSpec struct {
+	URL         string        `yaml:"url,omitempty" mapstructure:"url"`
+	SHA256      string        `yaml:"sha256,omitempty" mapstructure:"sha256"`             // only fits a fixed version
+	ChecksumURL string        `yaml:"checksum_url,omitempty" mapstructure:"checksum_url"` // lines of "<sha256>  <file name>", e.g. SHA256SUMS, or JSON with a sha256hash
+	Signature   SignatureSpec `yaml:"signature,omitempty" mapstructure:"signature"`
+	Run         string        `yaml:"run,omitempty" mapstructure:"run"` // e.g. "sh {{file}}" or "sudo dpkg -i {{file}}"
+}
+
+// SignatureSpec defines the detached signature of a download
+type SignatureSpec struct {
+	Type      string `yaml:"type,omitempty" mapstructure:"type"` // minisign, cosign or gpg
+	URL       string `yaml:"url,omitempty" mapstructure:"url"`
+	PublicKey string `yaml:"public_key,omitempty" mapstructure:"public_key"` // minisign key, or path of a cosign or armored GPG key
+	Of        string `yaml:"of,omitempty" mapstructure:"of"`                 // what is signed: file (default) or checksums
+}
+
+// SignatureTypes lists the supported signature.type values
+var SignatureTypes = []string{"minisign", "cosign", "gpg"}
+
+// ForMethod returns the command for the given install method, or "" if the spec has none
+func (s InstallSpec) ForMethod(method string) string {
+	switch method {
+	case "winget":
+		return s.WinGet
+	case "npm":
+		return s.Npm
+	case "brew":
+		return s.Brew
+	case "apt":
+		return s.Apt
+	case "pacman":
+		return s.Pacman
+	case "pip":
+		return s.Pip
+	case "script":
+		return s.Script
+	case "download":
+		// The command names the URL; it is replaced with the verified file before it runs
+		if s.Download.URL == "" {
+			return ""
+		}
+		return strings.ReplaceAll(s.Download.Run, FilePlaceholder, s.Download.URL)
+	default:
+		return ""
+	}
 }
 
 var (
@@ -60,42 +176,22 @@ var (
 	ToolsMap map[string]*ToolDefinition
 )
 
-// LoadToolDefinitions loads tool definitions from config file or embedded defaults
-func LoadToolDefinitions() error {
-	var configData []byte
-	var err error
-
-	// Try to load from external config file first
-	configPaths := []string{
-		"tools.yaml",
-		"config/tools.yaml",
-	}
-
-	// Add home directory config
-	if home, err := os.UserHomeDir(); err == nil {
-		configPaths = append(configPaths, filepath.Join(home, ".agenthelper", "tools.yaml"))
-	}
-
-	for _, path := range configPaths {
-		if data, err := os.ReadFile(path); err == nil {
-			configData = data
-			break
-		}
-	}
-
-	// Fall back to embedded config
-	if configData == nil {
-		configData, err = embeddedConfig.ReadFile("embedded_tools.yaml")
-		if err != nil {
-			return fmt.Errorf("failed to load embedded config: %w", err)
-		}
+// LoadToolDefinitions merges the embedded defaults, the user file, the project files and
+// the --config file (if any) by tool key, with later layers overriding individual fields
+func LoadToolDefinitions(configFile string) error {
+	layers, err := DiscoverLayers(configFile)
+	if err != nil {
+		return err
 	}
 
-	// Parse YAML
-	AppConfig = &Config{}
-	if err := yaml.Unmarshal(configData, AppConfig); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+	result, err := mergeLayers(layers)
+	if err != nil {
+		return err
 	}
+	AppConfig = result.config
+	LoadedLayers = layers
+	fieldOrigins = result.origins
+	mergedNodes = result.nodes
 
 	// Build tools map for quick access
 	ToolsMap = make(map[string]*ToolDefinition)
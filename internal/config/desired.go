@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// DesiredStateFile is the name of the file declaring which tools a machine should have
+const DesiredStateFile = "agents.yaml"
+
+// Desired tool states
+const (
+	StatePresent = "present"
+	StateAbsent  = "absent"
+)
+
+// InstallMethods lists the install methods an install spec can define
+var InstallMethods = []string{"winget", "npm", "brew", "apt", "pacman", "pip", "download", "script"}
+
+// DesiredState declares which tools must be present or absent, and at which versions
+type DesiredState struct {
+	Methods []string               `yaml:"methods,omitempty"` // preferred install methods, in order
+	Tools   map[string]DesiredTool `yaml:"tools"`
+	Files   []string               `yaml:"-"` // files the state was read from, lowest precedence first
+}
+
+// DesiredTool is the desired state of one tool. In YAML it can also be written as a
+// single value: "present", "absent" or a version constraint.
+type DesiredTool struct {
+	State   string `yaml:"state,omitempty"`   // present (default) or absent
+	Version string `yaml:"version,omitempty"` // exact version or semver range, e.g. "~1.2"
+	Method  string `yaml:"method,omitempty"`  // install method to use instead of the preferred ones
+}
+
+// UnmarshalYAML accepts the short forms of a desired tool
+func (t *DesiredTool) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		switch node.Value {
+		case "", StatePresent:
+			t.State = StatePresent
+		case StateAbsent:
+			t.State = StateAbsent
+		default:
+			t.State = StatePresent
+			t.Version = node.Value
+		}
+		return nil
+	}
+
+	type plain DesiredTool
+	if err := node.Decode((*plain)(t)); err != nil {
+		return err
+	}
+	if t.State == "" {
+		t.State = StatePresent
+	}
+	return nil
+}
+
+// Keys returns the tool keys of the desired state in sorted order
+func (d *DesiredState) Keys() []string {
+	keys := make([]string, 0, len(d.Tools))
+	for key := range d.Tools {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DesiredStatePaths returns the desired state files in precedence order, lowest first
+func DesiredStatePaths() []string {
+	var paths []string
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".agenthelper", DesiredStateFile))
+	}
+	return append(paths, DesiredStateFile)
+}
+
+// LoadDesiredState reads the desired state from the given file, or merges the user and
+// project files when file is empty. Project entries override user entries per tool.
+func LoadDesiredState(file string) (*DesiredState, error) {
+	paths := DesiredStatePaths()
+	if file != "" {
+		paths = []string{file}
+	}
+
+	state := &DesiredState{Tools: make(map[string]DesiredTool)}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) && file == "" {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		var layer DesiredState
+		if err := yaml.Unmarshal(data, &layer); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if err := layer.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if len(layer.Methods) > 0 {
+			state.Methods = layer.Methods
+		}
+		for key, tool := range layer.Tools {
+			state.Tools[key] = tool
+		}
+		state.Files = append(state.Files, path)
+	}
+
+	if len(state.Files) == 0 {
+		return nil, fmt.Errorf("no %s found in the current directory or ~/.agenthelper", DesiredStateFile)
+	}
+	return state, nil
+}
+
+func (d *DesiredState) validate() error {
+	var problems []string
+	for _, method := range d.Methods {
+		if !contains(InstallMethods, method) {
+			problems = append(problems, fmt.Sprintf("methods: unknown install method %q", method))
+		}
+	}
+	for _, key := range d.Keys() {
+		tool := d.Tools[key]
+		if _, ok := GetTool(key); !ok {
+			problems = append(problems, fmt.Sprintf("tools.%s: unknown tool", key))
+		}
+		if tool.State != StatePresent && tool.State != StateAbsent {
+			problems = append(problems, fmt.Sprintf("tools.%s: state must be %s or %s", key, StatePresent, StateAbsent))
+		}
+		if tool.Version != "" {
+			if tool.State == StateAbsent {
+				problems = append(problems, fmt.Sprintf("tools.%s: an absent tool cannot have a version", key))
+			} else if _, err := semver.NewConstraint(tool.Version); err != nil {
+				problems = append(problems, fmt.Sprintf("tools.%s: invalid version %q", key, tool.Version))
+			}
+		}
+		if tool.Method != "" && !contains(InstallMethods, tool.Method) {
+			problems = append(problems, fmt.Sprintf("tools.%s: unknown install method %q", key, tool.Method))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
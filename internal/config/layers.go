@@ -0,0 +1,282 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Layer names, from lowest to highest precedence
+const (
+	LayerEmbedded   = "embedded"
+	LayerCatalog    = "catalog"
+	LayerUser       = "user"
+	LayerProject    = "project"
+	LayerConfigFile = "config-file"
+)
+
+// Layer is a single source of tool definitions
+type Layer struct {
+	Name string
+	Path string // empty for the embedded layer
+	data []byte
+}
+
+// FieldOrigin records which layer set a field of a tool definition
+type FieldOrigin struct {
+	Path  string `json:"path"`
+	Value string `json:"value"`
+	Layer string `json:"layer"`
+	File  string `json:"file,omitempty"`
+}
+
+var (
+	// LoadedLayers lists the layers that contributed to the loaded configuration
+	LoadedLayers []Layer
+	// fieldOrigins maps tool key -> field path -> layer that set it
+	fieldOrigins map[string]map[string]*Layer
+	// mergedNodes holds the merged YAML of every tool, including disabled ones
+	mergedNodes map[string]*yaml.Node
+)
+
+// DiscoverLayers returns the tool definition layers in precedence order.
+// configFile is the --config file and may be empty. Catalog errors are reported
+// through CatalogError and only skip the catalog layer.
+func DiscoverLayers(configFile string) ([]Layer, error) {
+	embedded, err := embeddedConfig.ReadFile("embedded_tools.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded config: %w", err)
+	}
+	layers := []Layer{{Name: LayerEmbedded, data: embedded}}
+
+	catalog, err := loadCatalogLayer(GetCatalogSettings(), false)
+	CatalogError = err
+	if catalog != nil {
+		layers = append(layers, *catalog)
+	}
+
+	var candidates []Layer
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, Layer{Name: LayerUser, Path: filepath.Join(home, ".agenthelper", "tools.yaml")})
+	}
+	candidates = append(candidates,
+		Layer{Name: LayerProject, Path: filepath.Join("config", "tools.yaml")},
+		Layer{Name: LayerProject, Path: "tools.yaml"},
+	)
+	if configFile != "" {
+		candidates = append(candidates, Layer{Name: LayerConfigFile, Path: configFile})
+	}
+
+	for _, layer := range candidates {
+		data, err := os.ReadFile(layer.Path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", layer.Path, err)
+		}
+		layer.data = data
+		layers = append(layers, layer)
+	}
+
+	return layers, nil
+}
+
+// mergeResult holds the merged configuration together with the data needed to trace it
+type mergeResult struct {
+	config  *Config
+	nodes   map[string]*yaml.Node
+	origins map[string]map[string]*Layer
+}
+
+// mergeLayers merges the tools of every layer by key. Later layers override individual
+// fields of earlier ones; nested maps such as install specs are merged field by field.
+func mergeLayers(layers []Layer) (*mergeResult, error) {
+	var order []string
+	merged := make(map[string]*yaml.Node)
+	origins := make(map[string]map[string]*Layer)
+
+	for i := range layers {
+		layer := &layers[i]
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal(layer.data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", layer.Describe(), err)
+		}
+		tools := toolsSequence(&doc)
+		if tools == nil {
+			continue
+		}
+
+		for _, toolNode := range tools.Content {
+			if toolNode.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("%s:%d: tool definition must be a mapping", layer.Describe(), toolNode.Line)
+			}
+			keyNode := mappingValue(toolNode, "key")
+			if keyNode == nil || keyNode.Value == "" {
+				return nil, fmt.Errorf("%s:%d: tool definition without key", layer.Describe(), toolNode.Line)
+			}
+			key := keyNode.Value
+
+			if _, ok := merged[key]; !ok {
+				order = append(order, key)
+				merged[key] = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				origins[key] = make(map[string]*Layer)
+			}
+			mergeMapping(merged[key], toolNode, "", layer, origins[key])
+		}
+	}
+
+	cfg := &Config{}
+	for _, key := range order {
+		var tool ToolDefinition
+		if err := merged[key].Decode(&tool); err != nil {
+			return nil, fmt.Errorf("invalid definition for tool %s: %w", key, err)
+		}
+		if tool.Disabled {
+			continue
+		}
+		cfg.Tools = append(cfg.Tools, tool)
+	}
+
+	return &mergeResult{config: cfg, nodes: merged, origins: origins}, nil
+}
+
+// mergeMapping merges src into dst, recording the layer of every leaf that is set
+func mergeMapping(dst, src *yaml.Node, prefix string, layer *Layer, origins map[string]*Layer) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		name := src.Content[i].Value
+		value := src.Content[i+1]
+		path := joinPath(prefix, name)
+
+		existing := mappingValue(dst, name)
+		if existing != nil && path == "key" {
+			continue // The key identifies the tool; keep the layer that introduced it
+		}
+		if existing != nil && existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			mergeMapping(existing, value, path, layer, origins)
+			continue
+		}
+
+		// Replacing a whole subtree drops the origins recorded for it
+		for recorded := range origins {
+			if recorded == path || strings.HasPrefix(recorded, path+".") {
+				delete(origins, recorded)
+			}
+		}
+		recordOrigins(value, path, layer, origins)
+
+		if existing != nil {
+			*existing = *value
+		} else {
+			dst.Content = append(dst.Content, src.Content[i], value)
+		}
+	}
+}
+
+// recordOrigins marks every leaf below node as set by layer
+func recordOrigins(node *yaml.Node, path string, layer *Layer, origins map[string]*Layer) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			recordOrigins(node.Content[i+1], joinPath(path, node.Content[i].Value), layer, origins)
+		}
+		return
+	}
+	origins[path] = layer
+}
+
+// GetToolOrigins returns every field of a tool with the layer that set it, sorted by path.
+// Disabled tools are included so their origin can still be inspected.
+func GetToolOrigins(key string) []FieldOrigin {
+	node, ok := mergedNodes[key]
+	if !ok {
+		return nil
+	}
+
+	var fields []FieldOrigin
+	var walk func(n *yaml.Node, path string)
+	walk = func(n *yaml.Node, path string) {
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				walk(n.Content[i+1], joinPath(path, n.Content[i].Value))
+			}
+			return
+		case yaml.SequenceNode:
+			values := make([]string, 0, len(n.Content))
+			for _, item := range n.Content {
+				values = append(values, item.Value)
+			}
+			fields = append(fields, newFieldOrigin(key, path, "["+strings.Join(values, ", ")+"]"))
+		default:
+			fields = append(fields, newFieldOrigin(key, path, n.Value))
+		}
+	}
+	walk(node, "")
+
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Path < fields[j].Path })
+	return fields
+}
+
+// GetConfiguredToolKeys returns the keys of all merged tools, including disabled ones
+func GetConfiguredToolKeys() []string {
+	keys := make([]string, 0, len(mergedNodes))
+	for key := range mergedNodes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newFieldOrigin(key, path, value string) FieldOrigin {
+	origin := FieldOrigin{Path: path, Value: value}
+	if layer := fieldOrigins[key][path]; layer != nil {
+		origin.Layer = layer.Name
+		origin.File = layer.Path
+	}
+	return origin
+}
+
+// Describe returns the file path of a layer, or its name for the embedded layer
+func (l *Layer) Describe() string {
+	if l.Path == "" {
+		return l.Name
+	}
+	return l.Path
+}
+
+// toolsSequence returns the "tools" sequence of a YAML document, if any
+func toolsSequence(doc *yaml.Node) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	tools := mappingValue(doc.Content[0], "tools")
+	if tools == nil || tools.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return tools
+}
+
+// mappingValue returns the value for a key of a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// JSONSchema generates a JSON Schema for tool definition files from the Go types, so
+// editors can lint custom tools.yaml files
+func JSONSchema() map[string]interface{} {
+	schema := schemaForType(reflect.TypeOf(Config{}), "")
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "AgentHelper tool definitions"
+	return schema
+}
+
+func schemaForType(typ reflect.Type, path string) map[string]interface{} {
+	switch typ.Kind() {
+	case reflect.Struct:
+		fields := yamlFields(typ)
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		properties := make(map[string]interface{})
+		for _, name := range names {
+			properties[name] = schemaForType(fields[name].Type, joinPath(path, name))
+		}
+
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if path == "tools" {
+			schema["required"] = []string{"key"}
+		}
+		return schema
+	case reflect.Map:
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaForType(typ.Elem(), path),
+		}
+		if strings.HasSuffix(path, "install") {
+			schema["propertyNames"] = map[string]interface{}{"enum": OSKeys}
+		}
+		return schema
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaForType(typ.Elem(), path),
+		}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	default:
+		schema := map[string]interface{}{"type": "string"}
+		if path == "tools.version_source.type" {
+			schema["enum"] = VersionSourceTypes
+		}
+		if path == "tools.version_pattern" {
+			schema["format"] = "regex"
+		}
+		return schema
+	}
+}
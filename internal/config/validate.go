@@ -0,0 +1,428 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// VersionSourceTypes lists the supported version_source.type values, one per provider
+// registered by the manager package plus "unknown" for tools without a version source
+var VersionSourceTypes = []string{
+	"npm", "github", "github-tags", "pypi", "crates", "homebrew", "vscode-update",
+	"winget-pkgs", "http-json", "http-regex", "command", "unknown",
+}
+
+// requiredSourceFields lists the fields each version_source type needs
+var requiredSourceFields = map[string][]string{
+	"npm":         {"package"},
+	"github":      {"owner", "repo"},
+	"github-tags": {"owner", "repo"},
+	"pypi":        {"package"},
+	"crates":      {"package"},
+	"homebrew":    {"package"},
+	"winget-pkgs": {"package"},
+	"http-json":   {"url", "json_path"},
+	"http-regex":  {"url", "pattern"},
+	"command":     {"command"},
+}
+
+// OSKeys lists the supported keys of install and uninstall maps
+var OSKeys = []string{"windows", "darwin", "linux"}
+
+// ValidationError describes a problem in a tool definition file
+type ValidationError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// ValidateFile validates a tool definition file
+func ValidateFile(path string) ([]ValidationError, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return ValidateData(path, data), nil
+}
+
+// ValidateLayers validates every loaded layer and the merged result
+func ValidateLayers(layers []Layer) []ValidationError {
+	var errs []ValidationError
+	for i := range layers {
+		// The --config file also holds application settings next to its tools
+		errs = append(errs, validateData(layers[i].Describe(), layers[i].data, layers[i].Name == LayerConfigFile)...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// Required fields can come from any layer, so they are only checked after merging
+	result, err := mergeLayers(layers)
+	if err != nil {
+		return []ValidationError{{File: "merged", Message: err.Error()}}
+	}
+	for _, tool := range result.config.Tools {
+		if tool.Name == "" {
+			errs = append(errs, ValidationError{File: "merged", Message: fmt.Sprintf("tool %s: name is required", tool.Key)})
+		}
+		if tool.Command == "" {
+			errs = append(errs, ValidationError{File: "merged", Message: fmt.Sprintf("tool %s: command is required", tool.Key)})
+		}
+		for _, field := range missingSourceFields(tool.VersionSource) {
+			errs = append(errs, ValidationError{File: "merged", Message: fmt.Sprintf("tool %s: version_source type %s requires %s", tool.Key, tool.VersionSource.Type, field)})
+		}
+	}
+	for _, tool := range result.config.Tools {
+		for _, msg := range downloadErrors(tool) {
+			errs = append(errs, ValidationError{File: "merged", Message: msg})
+		}
+	}
+	for _, msg := range dependencyErrors(result.config.Tools) {
+		errs = append(errs, ValidationError{File: "merged", Message: msg})
+	}
+	return errs
+}
+
+// downloadErrors reports download installs that lack what is needed to verify and run them
+func downloadErrors(tool ToolDefinition) []string {
+	var msgs []string
+	for _, osKey := range OSKeys {
+		d := tool.Install[osKey].Download
+		if d == (DownloadSpec{}) {
+			continue
+		}
+		prefix := fmt.Sprintf("tool %s: install.%s.download", tool.Key, osKey)
+		if d.URL == "" {
+			msgs = append(msgs, prefix+" requires url")
+		}
+		if d.Run == "" {
+			msgs = append(msgs, prefix+" requires run")
+		}
+		if d.SHA256 == "" && d.ChecksumURL == "" {
+			msgs = append(msgs, prefix+" requires sha256 or checksum_url")
+		}
+		if sig := d.Signature; sig != (SignatureSpec{}) {
+			if sig.Type == "" || sig.URL == "" || sig.PublicKey == "" {
+				msgs = append(msgs, prefix+".signature requires type, url and public_key")
+			}
+			if sig.Of == "checksums" && d.ChecksumURL == "" {
+				msgs = append(msgs, prefix+".signature of checksums requires checksum_url")
+			}
+		}
+	}
+	return msgs
+}
+
+// dependencyErrors reports depends_on entries naming unknown tools and dependency cycles
+func dependencyErrors(tools []ToolDefinition) []string {
+	byKey := make(map[string]*ToolDefinition, len(tools))
+	for i := range tools {
+		byKey[tools[i].Key] = &tools[i]
+	}
+
+	var msgs []string
+	for _, tool := range tools {
+		for _, dep := range tool.DependsOn {
+			if _, ok := byKey[dep]; !ok {
+				msgs = append(msgs, fmt.Sprintf("tool %s: depends_on: unknown tool %q", tool.Key, dep))
+			}
+		}
+	}
+
+	// Depth-first search; a tool reached again while on the stack closes a cycle
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(tools))
+	var path []string
+	var visit func(key string)
+	visit = func(key string) {
+		state[key] = visiting
+		path = append(path, key)
+		for _, dep := range byKey[key].DependsOn {
+			if _, ok := byKey[dep]; !ok {
+				continue
+			}
+			switch state[dep] {
+			case visiting:
+				start := 0
+				for path[start] != dep {
+					start++
+				}
+				cycle := append(append([]string{}, path[start:]...), dep)
+				msgs = append(msgs, fmt.Sprintf("tool %s: dependency cycle %s", dep, strings.Join(cycle, " -> ")))
+			case unvisited:
+				visit(dep)
+			}
+		}
+		path = path[:len(path)-1]
+		state[key] = visited
+	}
+	for _, tool := range tools {
+		if state[tool.Key] == unvisited {
+			visit(tool.Key)
+		}
+	}
+	return msgs
+}
+
+// ValidateData validates the contents of a tool definition file. A file may be a partial
+// layer, so fields that can be inherited from other layers are not required here.
+func ValidateData(file string, data []byte) []ValidationError {
+	return validateData(file, data, false)
+}
+
+func validateData(file string, data []byte, toolsOnly bool) []ValidationError {
+	v := &validator{file: file}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		v.errs = append(v.errs, ValidationError{File: file, Message: err.Error()})
+		return v.errs
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+
+	tools := toolsSequence(&doc)
+	if !toolsOnly {
+		v.checkNode(doc.Content[0], reflect.TypeOf(Config{}), "")
+	} else if tools != nil {
+		v.checkNode(tools, reflect.TypeOf(Config{}.Tools), "tools")
+	}
+	if tools != nil {
+		v.checkTools(tools)
+	}
+	return v.errs
+}
+
+type validator struct {
+	file string
+	errs []ValidationError
+}
+
+func (v *validator) add(node *yaml.Node, format string, a ...interface{}) {
+	v.errs = append(v.errs, ValidationError{
+		File:    v.file,
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// checkNode checks a node against the Go type it decodes into, reporting unknown fields
+// and values of the wrong kind
+func (v *validator) checkNode(node *yaml.Node, typ reflect.Type, path string) {
+	switch typ.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			v.add(node, "%s must be a mapping", describePath(path))
+			return
+		}
+		fields := yamlFields(typ)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name := node.Content[i].Value
+			field, ok := fields[name]
+			if !ok {
+				v.add(node.Content[i], "unknown field %q in %s", name, describePath(path))
+				continue
+			}
+			v.checkNode(node.Content[i+1], field.Type, joinPath(path, name))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.add(node, "%s must be a mapping", describePath(path))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.checkNode(node.Content[i+1], typ.Elem(), joinPath(path, node.Content[i].Value))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			v.add(node, "%s must be a list", describePath(path))
+			return
+		}
+		for _, item := range node.Content {
+			v.checkNode(item, typ.Elem(), path)
+		}
+	default:
+		if node.Kind != yaml.ScalarNode {
+			v.add(node, "%s must be a %s", describePath(path), typ.Kind())
+			return
+		}
+		if err := node.Decode(reflect.New(typ).Interface()); err != nil {
+			v.add(node, "%s must be a %s", describePath(path), typ.Kind())
+		}
+	}
+}
+
+// checkTools runs the checks that need more than the shape of a tool definition
+func (v *validator) checkTools(tools *yaml.Node) {
+	seen := make(map[string]*yaml.Node)
+
+	for _, tool := range tools.Content {
+		if tool.Kind != yaml.MappingNode {
+			continue
+		}
+
+		keyNode := mappingValue(tool, "key")
+		if keyNode == nil || keyNode.Value == "" {
+			v.add(tool, "tool definition without key")
+			continue
+		}
+		if first, ok := seen[keyNode.Value]; ok {
+			v.add(keyNode, "duplicate tool key %q (first defined on line %d)", keyNode.Value, first.Line)
+		} else {
+			seen[keyNode.Value] = keyNode
+		}
+
+		if pattern := mappingValue(tool, "version_pattern"); pattern != nil && pattern.Value != "" {
+			if _, err := regexp.Compile(pattern.Value); err != nil {
+				v.add(pattern, "invalid version_pattern: %v", err)
+			}
+		}
+
+		if constraint := mappingValue(tool, "version"); constraint != nil && constraint.Value != "" {
+			if _, err := semver.NewConstraint(constraint.Value); err != nil {
+				v.add(constraint, "invalid version constraint %q: %v", constraint.Value, err)
+			}
+		}
+
+		if source := mappingValue(tool, "version_source"); source != nil {
+			if typeNode := mappingValue(source, "type"); typeNode != nil && !contains(VersionSourceTypes, typeNode.Value) {
+				v.add(typeNode, "unknown version_source type %q (expected one of %s)", typeNode.Value, strings.Join(VersionSourceTypes, ", "))
+			}
+			if pattern := mappingValue(source, "pattern"); pattern != nil && pattern.Value != "" {
+				if _, err := regexp.Compile(pattern.Value); err != nil {
+					v.add(pattern, "invalid version_source pattern: %v", err)
+				}
+			}
+		}
+
+		if timeouts := mappingValue(tool, "timeout"); timeouts != nil && timeouts.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(timeouts.Content); i += 2 {
+				value := timeouts.Content[i+1]
+				if d, err := time.ParseDuration(value.Value); err != nil || d <= 0 {
+					v.add(value, "invalid timeout.%s %q (expected a duration like 30s or 5m)", timeouts.Content[i].Value, value.Value)
+				}
+			}
+		}
+
+		for _, section := range []string{"install", "uninstall"} {
+			specs := mappingValue(tool, section)
+			if specs == nil || specs.Kind != yaml.MappingNode {
+				continue
+			}
+			for i := 0; i+1 < len(specs.Content); i += 2 {
+				osNode, spec := specs.Content[i], specs.Content[i+1]
+				if !contains(OSKeys, osNode.Value) {
+					v.add(osNode, "unknown OS %q in %s (expected one of %s)", osNode.Value, section, strings.Join(OSKeys, ", "))
+				}
+				if spec.Kind == yaml.MappingNode && !hasNonEmptyValue(spec) {
+					v.add(spec, "%s.%s defines no methods", section, osNode.Value)
+				}
+				if download := mappingValue(spec, "download"); download != nil && download.Kind == yaml.MappingNode {
+					v.checkDownload(download, section+"."+osNode.Value+".download")
+				}
+			}
+		}
+	}
+}
+
+// checkDownload checks the values of a download install
+func (v *validator) checkDownload(download *yaml.Node, path string) {
+	if sum := mappingValue(download, "sha256"); sum != nil && !sha256Pattern.MatchString(sum.Value) {
+		v.add(sum, "invalid %s.sha256 (expected 64 hex digits)", path)
+	}
+	if run := mappingValue(download, "run"); run != nil && !strings.Contains(run.Value, FilePlaceholder) {
+		v.add(run, "%s.run must contain %s", path, FilePlaceholder)
+	}
+	signature := mappingValue(download, "signature")
+	if signature == nil {
+		return
+	}
+	if typeNode := mappingValue(signature, "type"); typeNode != nil && !contains(SignatureTypes, typeNode.Value) {
+		v.add(typeNode, "unknown %s.signature type %q (expected one of %s)", path, typeNode.Value, strings.Join(SignatureTypes, ", "))
+	}
+	if of := mappingValue(signature, "of"); of != nil && of.Value != "file" && of.Value != "checksums" {
+		v.add(of, "invalid %s.signature of %q (expected file or checksums)", path, of.Value)
+	}
+}
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// yamlFields maps yaml field names of a struct type to their struct fields
+func yamlFields(typ reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// missingSourceFields returns the required fields of a version source that are empty
+func missingSourceFields(source VersionSource) []string {
+	fields := yamlFields(reflect.TypeOf(source))
+	value := reflect.ValueOf(source)
+
+	var missing []string
+	for _, name := range requiredSourceFields[source.Type] {
+		if value.FieldByIndex(fields[name].Index).IsZero() {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+func hasNonEmptyValue(node *yaml.Node) bool {
+	for i := 1; i < len(node.Content); i += 2 {
+		if node.Content[i].Value != "" || len(node.Content[i].Content) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "document"
+	}
+	return path
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
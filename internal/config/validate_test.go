@@ -0,0 +1,36 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateVersionConstraint(t *testing.T) {
+	tests := []struct {
+		version string
+		wantErr string // empty when the definition is valid
+	}{
+		{`"~1.2"`, ""},
+		{`">=0.40 <0.50"`, ""},
+		{`"^2 || ^3"`, ""},
+		{`"1.2.x || banana"`, `5:14: invalid version constraint "1.2.x || banana"`},
+		{`"latest"`, `5:14: invalid version constraint "latest"`},
+	}
+	for _, tt := range tests {
+		data := "tools:\n" +
+			"  - key: tool\n" +
+			"    name: Tool\n" +
+			"    command: tool\n" +
+			"    version: " + tt.version + "\n" +
+			"    install:\n" +
+			"      linux:\n" +
+			"        npm: \"npm install -g tool\"\n"
+		errs := ValidateData("tools.yaml", []byte(data))
+		switch {
+		case tt.wantErr == "" && len(errs) > 0:
+			t.Errorf("version %s: ValidateData() = %v, want no errors", tt.version, errs)
+		case tt.wantErr != "" && (len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantErr)):
+			t.Errorf("version %s: ValidateData() = %v, want %q", tt.version, errs, tt.wantErr)
+		}
+	}
+}
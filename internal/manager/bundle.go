@@ -0,0 +1,520 @@
+package manager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+	"github.com/jschneider/agenthelper/internal/ui"
+)
+
+// BundleManifestName is the file name of the manifest inside a bundle
+const BundleManifestName = "manifest.json"
+
+// BundleManifest describes the packages in a bundle and the platform they are for
+type BundleManifest struct {
+	Created time.Time         `json:"created"`
+	OS      platform.OS       `json:"os"`
+	Arch    platform.Arch     `json:"arch"`
+	Tools   []BundledTool     `json:"tools"`
+	Files   map[string]string `json:"files"` // SHA-256 of every file in the bundle, including dependencies
+}
+
+// BundledTool is the package of a tool in a bundle
+type BundledTool struct {
+	Key     string `json:"key"`
+	Version string `json:"version"`
+	Method  string `json:"method"` // npm, pip, apt or script, as for mirror packages
+	File    string `json:"file"`
+}
+
+// BundleResult represents the result of creating a bundle
+type BundleResult struct {
+	Success  bool
+	Path     string
+	Manifest *BundleManifest
+	Failed   map[string]error // tools left out of the bundle
+	Error    error
+}
+
+// pipPlatforms maps platforms to the wheel platform tag pip downloads for
+var pipPlatforms = map[string]string{
+	"linux/amd64":   "manylinux2014_x86_64",
+	"linux/arm64":   "manylinux2014_aarch64",
+	"darwin/amd64":  "macosx_10_9_x86_64",
+	"darwin/arm64":  "macosx_11_0_arm64",
+	"windows/amd64": "win_amd64",
+	"windows/386":   "win32",
+}
+
+// archNames lists how release assets name each architecture
+var archNames = map[platform.Arch][]string{
+	platform.AMD64: {"amd64", "x86_64", "x64"},
+	platform.ARM64: {"arm64", "aarch64"},
+	platform.I386:  {"386", "i386", "i686"},
+}
+
+// CreateBundle downloads the latest allowed version of each tool for the target platform
+// and packs them with a manifest into a gzipped tar archive. A tool's package is the
+// first of its mirror kinds that can be downloaded: a release asset matching its deb or
+// AppImage mirror pattern, an npm pack tarball, or pip wheels.
+func (m *Manager) CreateBundle(ctx context.Context, tools []config.ToolDefinition, target *platform.Platform, output string) *BundleResult {
+	result := &BundleResult{Path: output, Failed: make(map[string]error)}
+	dir, err := os.MkdirTemp("", "agenthelper-bundle-")
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	defer os.RemoveAll(dir)
+
+	manifest := &BundleManifest{Created: time.Now().UTC(), OS: target.OS, Arch: target.Arch}
+	for _, tool := range inDependencyOrder(tools, tools) {
+		tool := tool
+		ui.Info("Downloading %s for %s...", tool.Name, target.String())
+		entry, err := m.bundleTool(ctx, &tool, target, dir)
+		if err != nil {
+			result.Failed[tool.Key] = err
+			continue
+		}
+		manifest.Tools = append(manifest.Tools, *entry)
+	}
+	if len(manifest.Tools) == 0 {
+		result.Error = fmt.Errorf("none of the tools could be downloaded")
+		return result
+	}
+
+	if manifest.Files, err = checksumFiles(dir); err != nil {
+		result.Error = err
+		return result
+	}
+	if err := writeBundle(output, dir, manifest); err != nil {
+		result.Error = fmt.Errorf("failed to write bundle: %w", err)
+		return result
+	}
+	result.Success = true
+	result.Manifest = manifest
+	return result
+}
+
+// bundleTool downloads the package of a tool into dir
+func (m *Manager) bundleTool(ctx context.Context, tool *config.ToolDefinition, target *platform.Platform, dir string) (*BundledTool, error) {
+	version, err := m.versions.LatestAllowedVersion(ctx, tool)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the version of %s: %w", tool.Name, err)
+	}
+
+	spec := tool.Install[target.GetOSKey()]
+	var failures []string
+	for _, kind := range mirrorKinds {
+		if kind.linux && target.OS != platform.Linux {
+			continue
+		}
+		var file string
+		switch kind.method {
+		case "npm":
+			if spec.Npm == "" {
+				continue
+			}
+			file, err = m.npmPack(ctx, tool, spec.Npm, version, dir)
+		case "pip":
+			if spec.Pip == "" {
+				continue
+			}
+			file, err = m.pipDownload(ctx, tool, spec.Pip, version, target, dir)
+		default:
+			pattern := kind.pattern(tool.Mirror)
+			if pattern == "" {
+				continue
+			}
+			file, err = m.downloadReleaseAsset(ctx, tool, pattern, version, target.Arch, dir)
+		}
+		if err == nil {
+			return &BundledTool{Key: tool.Key, Version: version, Method: kind.method, File: file}, nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", kind.method, err))
+	}
+
+	if len(failures) == 0 {
+		return nil, fmt.Errorf("%s has no npm, pip or release package for %s", tool.Name, target.String())
+	}
+	return nil, fmt.Errorf("could not download %s: %s", tool.Name, strings.Join(failures, "; "))
+}
+
+// npmPack downloads the tarball of an npm package at a version
+func (m *Manager) npmPack(ctx context.Context, tool *config.ToolDefinition, command, version, dir string) (string, error) {
+	pinned, err := PinCommand("npm", command, version)
+	if err != nil {
+		return "", err
+	}
+	before := listFiles(dir)
+	line := fmt.Sprintf("npm pack %s --pack-destination %s", m.sys.QuoteArg(packageArg(pinned)), m.sys.QuoteArg(dir))
+	if err := m.runBundleCommand(ctx, tool, "npm", applyRegistry(tool, "npm", line)); err != nil {
+		return "", err
+	}
+	for _, name := range newFiles(dir, before) {
+		if strings.HasSuffix(name, ".tgz") {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("npm pack created no tarball")
+}
+
+// pipDownload downloads the wheel of a Python package at a version and the wheels of its
+// dependencies. Wheels for another platform are downloaded as binaries only.
+func (m *Manager) pipDownload(ctx context.Context, tool *config.ToolDefinition, command, version string, target *platform.Platform, dir string) (string, error) {
+	pinned, err := PinCommand("pip", command, version)
+	if err != nil {
+		return "", err
+	}
+	pip := strings.Fields(command)[0]
+	line := fmt.Sprintf("%s download %s --dest %s", pip, m.sys.QuoteArg(packageArg(pinned)), m.sys.QuoteArg(dir))
+	if target.OS != m.platform.OS || target.Arch != m.platform.Arch {
+		tag, ok := pipPlatforms[string(target.OS)+"/"+string(target.Arch)]
+		if !ok {
+			return "", fmt.Errorf("no wheel platform known for %s", target.String())
+		}
+		line += " --platform " + tag + " --only-binary=:all:"
+	}
+
+	before := listFiles(dir)
+	if err := m.runBundleCommand(ctx, tool, "pip", applyRegistry(tool, "pip", line)); err != nil {
+		return "", err
+	}
+
+	// The wheel of the package itself, e.g. aider_chat-0.86.1-py3-none-any.whl for aider-chat
+	name := packageArg(command)
+	if idx := strings.IndexAny(name, "[=<>~!"); idx > 0 {
+		name = name[:idx]
+	}
+	prefix := normalizePythonName(name) + "_"
+	for _, file := range newFiles(dir, before) {
+		if strings.HasPrefix(normalizePythonName(file), prefix) && SameVersion(ExtractVersion(file, ""), version) {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("pip downloaded no package of %s %s", name, version)
+}
+
+// downloadReleaseAsset downloads the asset of a GitHub release that matches a mirror
+// pattern and the architecture
+func (m *Manager) downloadReleaseAsset(ctx context.Context, tool *config.ToolDefinition, pattern, version string, arch platform.Arch, dir string) (string, error) {
+	source := tool.VersionSource
+	if source.Type != "github" {
+		return "", fmt.Errorf("release packages are only downloaded for github version sources")
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, tool.CheckTimeout())
+	defer cancel()
+	assets, err := m.versions.gitHubReleaseAssets(lookupCtx, m.versions.githubAPIBase(source), source.Owner, source.Repo, version)
+	if err != nil {
+		return "", err
+	}
+	for _, asset := range assets {
+		if ok, _ := filepath.Match(pattern, asset.Name); ok && matchesArch(asset.Name, arch) {
+			req, err := m.versions.newGitHubRequest(ctx, asset.URL)
+			if err != nil {
+				return "", err
+			}
+			req.Header.Set("Accept", "application/octet-stream")
+			return asset.Name, m.versions.download(req, filepath.Join(dir, asset.Name), maxDownloadSize)
+		}
+	}
+	return "", fmt.Errorf("release %s has no asset matching %s for %s", version, pattern, arch)
+}
+
+// runBundleCommand runs a download command of a bundle
+func (m *Manager) runBundleCommand(ctx context.Context, tool *config.ToolDefinition, method, command string) error {
+	ctx, cancel := context.WithTimeout(ctx, tool.InstallTimeout())
+	defer cancel()
+	out, err := m.runToolCommand(ctx, tool, "bundle", method, command)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w\n%s", command, err, out.stderr.String())
+	}
+	return nil
+}
+
+// InstallBundle verifies a bundle created with CreateBundle and installs its tools, or
+// only the given ones. Tools already installed at the bundled version are skipped.
+func (m *Manager) InstallBundle(ctx context.Context, path string, keys []string) (map[string]*InstallResult, error) {
+	dir, err := os.MkdirTemp("", "agenthelper-bundle-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	manifest, err := extractBundle(path, dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.OS != m.platform.OS || manifest.Arch != m.platform.Arch {
+		return nil, fmt.Errorf("bundle is for %s/%s, not %s", manifest.OS, manifest.Arch, m.platform.String())
+	}
+
+	wanted := make(map[string]bool)
+	for _, key := range keys {
+		wanted[key] = true
+	}
+	results := make(map[string]*InstallResult)
+	for _, entry := range manifest.Tools {
+		if len(keys) > 0 && !wanted[entry.Key] {
+			continue
+		}
+		delete(wanted, entry.Key)
+
+		tool, ok := config.GetTool(entry.Key)
+		if !ok {
+			results[entry.Key] = &InstallResult{Success: false, Error: fmt.Errorf("unknown tool: %s", entry.Key)}
+			continue
+		}
+		if current, err := m.GetInstalledVersion(ctx, tool); err == nil && SameVersion(current, entry.Version) {
+			results[entry.Key] = &InstallResult{Success: true, Output: "Already installed"}
+			continue
+		}
+
+		pkg := &mirrorPackage{Method: entry.Method, Path: filepath.Join(dir, entry.File), Version: entry.Version}
+		command, err := m.mirrorCommand(tool, pkg)
+		if err != nil {
+			results[entry.Key] = &InstallResult{Success: false, Method: entry.Method, Error: err}
+			continue
+		}
+		results[entry.Key] = m.runInstall(ctx, tool, entry.Method, command)
+	}
+	for key := range wanted {
+		results[key] = &InstallResult{Success: false, Error: fmt.Errorf("%s is not in the bundle", key)}
+	}
+	return results, nil
+}
+
+// writeBundle packs the manifest and the files of dir into a gzipped tar archive
+func writeBundle(output, dir string, manifest *BundleManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	if err := addToBundle(tw, BundleManifestName, bytes.NewReader(data), int64(len(data))); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(manifest.Files))
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		info, err := f.Stat()
+		if err == nil {
+			err = addToBundle(tw, name, f, info.Size())
+		}
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func addToBundle(tw *tar.Writer, name string, r io.Reader, size int64) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: size, ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+// extractBundle unpacks a bundle into dir and verifies every file against the manifest
+func extractBundle(path, dir string) (*BundleManifest, error) {
+	var manifest *BundleManifest
+	err := readBundle(path, func(name string, r io.Reader) error {
+		if name == BundleManifestName {
+			manifest = &BundleManifest{}
+			return json.NewDecoder(r).Decode(manifest)
+		}
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(file, r); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%s has no %s", path, BundleManifestName)
+	}
+
+	sums, err := checksumFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for name, want := range manifest.Files {
+		got, ok := sums[name]
+		if !ok {
+			return nil, fmt.Errorf("bundle is missing %s", name)
+		}
+		if got != want {
+			return nil, fmt.Errorf("checksum mismatch for %s: the bundle is corrupt or was modified", name)
+		}
+		delete(sums, name)
+	}
+	if len(sums) > 0 {
+		extra := make([]string, 0, len(sums))
+		for name := range sums {
+			extra = append(extra, name)
+		}
+		sort.Strings(extra)
+		return nil, fmt.Errorf("bundle contains files that are not in the manifest: %s", strings.Join(extra, ", "))
+	}
+	for _, entry := range manifest.Tools {
+		if _, ok := manifest.Files[entry.File]; !ok {
+			return nil, fmt.Errorf("bundle is missing the package of %s", entry.Key)
+		}
+	}
+	return manifest, nil
+}
+
+// readBundle calls fn for every file of a bundle. Only plain files at the top level are
+// accepted, so that a bundle cannot write outside the directory it is extracted to.
+func readBundle(path string, fn func(name string, r io.Reader) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("%s is not a bundle: %w", path, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || header.Name != filepath.Base(header.Name) || strings.HasPrefix(header.Name, ".") {
+			return fmt.Errorf("bundle contains unexpected entry %q", header.Name)
+		}
+		if err := fn(header.Name, tr); err != nil {
+			return fmt.Errorf("failed to read %s from bundle: %w", header.Name, err)
+		}
+	}
+}
+
+// checksumFiles returns the SHA-256 of every file in dir
+func checksumFiles(dir string) (map[string]string, error) {
+	sums := make(map[string]string)
+	for name := range listFiles(dir) {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		sums[name] = hex.EncodeToString(h.Sum(nil))
+	}
+	return sums, nil
+}
+
+// listFiles returns the names of the files in dir
+func listFiles(dir string) map[string]bool {
+	files := make(map[string]bool)
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			files[entry.Name()] = true
+		}
+	}
+	return files
+}
+
+// newFiles returns the files in dir that are not in before, sorted
+func newFiles(dir string, before map[string]bool) []string {
+	var added []string
+	for name := range listFiles(dir) {
+		if !before[name] {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	return added
+}
+
+// packageArg returns the package argument of an install command, e.g. "aider-chat==0.86.1"
+func packageArg(command string) string {
+	if idx := strings.Index(command, "&&"); idx >= 0 {
+		command = command[:idx]
+	}
+	var pkg string
+	pinPackageArg(command, func(arg string) string {
+		pkg = arg
+		return arg
+	})
+	return pkg
+}
+
+// normalizePythonName makes Python package and wheel names comparable, as in PEP 503 and 427
+func normalizePythonName(name string) string {
+	return strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToLower(name))
+}
+
+// matchesArch reports whether a release asset is for the architecture: it names the
+// architecture, or none at all
+func matchesArch(name string, arch platform.Arch) bool {
+	name = strings.ToLower(name)
+	other := false
+	for a, names := range archNames {
+		for _, n := range names {
+			if strings.Contains(name, n) {
+				if a == arch {
+					return true
+				}
+				other = true
+			}
+		}
+	}
+	return !other
+}
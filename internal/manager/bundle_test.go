@@ -0,0 +1,119 @@
+package manager
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+	"github.com/jschneider/agenthelper/internal/platform/platformtest"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	m, runner := newTestManager(t, platform.Linux, "apt")
+	server := newFixtureServer(t)
+	m.versions, _ = newTestChecker(t, server)
+
+	tool := testTool("tool", map[string]config.InstallSpec{"linux": {Apt: "apt install tool"}})
+	tool.VersionSource = config.VersionSource{Type: "github", Owner: "acme", Repo: "tool"}
+	tool.Mirror = config.MirrorSpec{Deb: "tool_*_linux_*.deb"}
+	setTools(t, tool)
+
+	arch := m.GetPlatform().Arch
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	result := m.CreateBundle(context.Background(), []config.ToolDefinition{tool}, &platform.Platform{OS: platform.Linux, Arch: arch}, path)
+	if !result.Success {
+		t.Fatalf("CreateBundle() failed: %v %v", result.Error, result.Failed)
+	}
+
+	asset := "tool_2.3.0_linux_" + string(arch) + ".deb"
+	want := BundledTool{Key: "tool", Version: "2.3.0", Method: "apt", File: asset}
+	if len(result.Manifest.Tools) != 1 || result.Manifest.Tools[0] != want {
+		t.Fatalf("bundled %+v, want %+v", result.Manifest.Tools, want)
+	}
+	if len(result.Manifest.Files) != 1 || result.Manifest.Files[asset] == "" {
+		t.Errorf("manifest files = %v, want the checksum of %s", result.Manifest.Files, asset)
+	}
+
+	runner.On("apt install", platformtest.Response{})
+	runner.On("tool --version", platformtest.Response{Stdout: "2.2.1\n"})
+	results, err := m.InstallBundle(context.Background(), path, nil)
+	if err != nil {
+		t.Fatalf("InstallBundle() failed: %v", err)
+	}
+	if r := results["tool"]; r == nil || !r.Success {
+		t.Fatalf("InstallBundle() = %+v, want tool installed", r)
+	}
+	ran := changes(runner)
+	if len(ran) != 1 || !strings.HasPrefix(ran[0], "apt install -y ") || !strings.Contains(ran[0], asset) {
+		t.Errorf("ran %q, want apt installing %s", ran, asset)
+	}
+
+	runner.On("tool --version", platformtest.Response{Stdout: "2.3.0\n"})
+	results, _ = m.InstallBundle(context.Background(), path, nil)
+	if r := results["tool"]; r == nil || r.Output != "Already installed" {
+		t.Errorf("InstallBundle() = %+v, want the installed version skipped", r)
+	}
+
+	results, _ = m.InstallBundle(context.Background(), path, []string{"other"})
+	if r := results["other"]; r == nil || r.Error == nil || !strings.Contains(r.Error.Error(), "not in the bundle") {
+		t.Errorf("InstallBundle(other) = %+v, want an error", r)
+	}
+}
+
+func TestInstallBundleRejects(t *testing.T) {
+	m, _ := newTestManager(t, platform.Linux, "apt")
+	arch := m.GetPlatform().Arch
+	manifest := `{"os": "linux", "arch": "` + string(arch) + `", "tools": [{"key": "tool", "version": "1.0.0", "method": "apt", "file": "tool.deb"}], ` +
+		`"files": {"tool.deb": "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"}}`
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{"modified package", map[string]string{"manifest.json": manifest, "tool.deb": "tampered"}, "checksum mismatch"},
+		{"missing package", map[string]string{"manifest.json": manifest}, "missing tool.deb"},
+		{"extra file", map[string]string{"manifest.json": manifest, "tool.deb": "hello world", "extra.sh": "x"}, "not in the manifest"},
+		{"path outside the bundle", map[string]string{"manifest.json": manifest, "../tool.deb": "hello world"}, "unexpected entry"},
+		{"other platform", map[string]string{"manifest.json": strings.Replace(manifest, `"linux"`, `"windows"`, 1), "tool.deb": "hello world"}, "bundle is for windows"},
+		{"no manifest", map[string]string{"tool.deb": "hello world"}, "has no manifest.json"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+			writeTestBundle(t, path, tt.files)
+
+			_, err := m.InstallBundle(context.Background(), path, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("InstallBundle() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// writeTestBundle writes a gzipped tar archive of the files, by name
+func writeTestBundle(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := addToBundle(tw, name, strings.NewReader(content), int64(len(content))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+}
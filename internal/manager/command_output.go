@@ -0,0 +1,88 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+	"github.com/jschneider/agenthelper/internal/ui"
+)
+
+// StreamsOutput reports whether command output is shown while commands run
+func (m *Manager) StreamsOutput() bool {
+	return m.opts.StreamOutput
+}
+
+// commandOutput is the captured output of a command
+type commandOutput struct {
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+	log    lockedBuffer // stdout and stderr in the order they were written
+}
+
+// lockedBuffer is a buffer that stdout and stderr can be written to concurrently
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// promptingCommand matches commands that may ask for input on the terminal, such as a
+// sudo password
+var promptingCommand = regexp.MustCompile(`(^|[\s;&|(])(sudo|doas|su)\s`)
+
+// mayPrompt reports whether a command may ask for input on the terminal. Scripts and
+// downloaded installers are assumed to.
+func mayPrompt(method, command string) bool {
+	return method == "script" || method == "download" || promptingCommand.MatchString(command)
+}
+
+// runToolCommand runs an install, update or uninstall command of a tool, captures its
+// output and writes an operation log. In streaming mode the output is also shown live,
+// each line prefixed with the tool key, and a command that may prompt, e.g. for a sudo
+// password, reads from the terminal. Only one such command runs at a time, so scheduled
+// operations don't prompt over each other.
+func (m *Manager) runToolCommand(ctx context.Context, tool *config.ToolDefinition, step, method, command string) (*commandOutput, error) {
+	cmd := &platform.Command{Line: command}
+	if env := registryEnv(tool, method); env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	entry := newOperationLog(ctx, tool, step, method, command, cmd.Env)
+	out := &commandOutput{}
+	stdout := io.MultiWriter(&out.stdout, &out.log)
+	stderr := io.MultiWriter(&out.stderr, &out.log)
+
+	if m.opts.StreamOutput {
+		live := ui.NewPrefixWriter(tool.Key)
+		defer live.Close()
+		stdout = io.MultiWriter(stdout, live)
+		stderr = io.MultiWriter(stderr, live)
+		if mayPrompt(method, command) {
+			m.terminal.Lock()
+			defer m.terminal.Unlock()
+			cmd.Stdin = os.Stdin
+		}
+	}
+
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := m.sys.Runner.Run(ctx, cmd)
+	entry.finish(out, err)
+	return out, err
+}
@@ -0,0 +1,207 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/jschneider/agenthelper/internal/config"
+)
+
+// Drift is a difference between the desired and the actual state of a tool
+type Drift struct {
+	Tool         *config.ToolDefinition // carries the desired version as its constraint
+	Desired      config.DesiredTool
+	Action       string // install, update or uninstall; empty when the tool is as desired
+	InstalledVer string
+	TargetVer    string // version an install or update would go to, if known
+	Method       string // install method the action would use
+	Reason       string
+	Error        error // the action cannot be carried out
+}
+
+// ApplyResult is the outcome of converging one tool
+type ApplyResult struct {
+	Action  string
+	Success bool
+	Output  string
+	Log     string
+	Error   error
+}
+
+// Diff compares the desired state with the installed tools. Missing dependencies of
+// present tools are included as installs unless the state lists them itself.
+func (m *Manager) Diff(ctx context.Context, state *config.DesiredState) []*Drift {
+	var tools []config.ToolDefinition
+	var desired []config.DesiredTool
+	for _, key := range state.Keys() {
+		tool, ok := config.GetTool(key)
+		if !ok {
+			continue
+		}
+		t := *tool
+		t.Version = state.Tools[key].Version
+		tools = append(tools, t)
+		desired = append(desired, state.Tools[key])
+	}
+
+	drifts := make([]*Drift, len(tools))
+	forEachTool(tools, statusConcurrency, func(idx int, tool *config.ToolDefinition) {
+		drifts[idx] = m.toolDrift(ctx, tool, desired[idx], state.Methods)
+	})
+
+	// Dependencies that the state does not mention are installed along with their dependents
+	seen := make(map[string]bool)
+	for _, d := range drifts {
+		if d.Desired.State != config.StatePresent {
+			continue
+		}
+		for _, dep := range m.MissingDependencies(ctx, d.Tool) {
+			if _, listed := state.Tools[dep.Key]; listed || seen[dep.Key] {
+				continue
+			}
+			seen[dep.Key] = true
+			dep := dep
+			drift := m.toolDrift(ctx, &dep, config.DesiredTool{State: config.StatePresent}, state.Methods)
+			drift.Reason = "required by " + d.Tool.Name
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts
+}
+
+// toolDrift compares the desired state of one tool with its status
+func (m *Manager) toolDrift(ctx context.Context, tool *config.ToolDefinition, desired config.DesiredTool, methods []string) *Drift {
+	drift := &Drift{Tool: tool, Desired: desired}
+
+	status := m.GetToolStatus(ctx, tool)
+	drift.InstalledVer = status.InstalledVer
+
+	if desired.State == config.StateAbsent {
+		if status.IsInstalled {
+			drift.Action = "uninstall"
+			drift.Reason = "should be absent"
+			drift.Method, _ = m.InstalledMethod(ctx, tool)
+		}
+		return drift
+	}
+
+	if !status.IsInstalled {
+		drift.Action = "install"
+		drift.Reason = "not installed"
+		drift.TargetVer = status.TargetVer
+		drift.Method = m.desiredMethod(tool, desired, methods)
+		if drift.Method == "" {
+			drift.Error = fmt.Errorf("no installation method available for %s on %s", tool.Name, m.platform.String())
+		} else if tool.Version != "" && drift.TargetVer == "" {
+			drift.Error = fmt.Errorf("no version of %s matching %s could be found", tool.Name, tool.Version)
+		}
+		return drift
+	}
+
+	if tool.Version == "" {
+		return drift
+	}
+	constraint, err := semver.NewConstraint(tool.Version)
+	if err != nil {
+		drift.Error = err
+		return drift
+	}
+	installed, err := semver.NewVersion(strings.TrimPrefix(status.InstalledVer, "v"))
+	if err == nil && constraint.Check(installed) {
+		return drift
+	}
+
+	drift.Action = "update"
+	drift.Reason = fmt.Sprintf("v%s does not match %s", status.InstalledVer, tool.Version)
+	drift.Method = status.Method
+	drift.TargetVer = status.TargetVer
+	if drift.TargetVer == "" {
+		drift.Error = fmt.Errorf("no version of %s matching %s could be found", tool.Name, tool.Version)
+	}
+	return drift
+}
+
+// desiredMethod returns the install method for a tool: the tool's own method, else the
+// first preferred method the tool supports here, else the best available one
+func (m *Manager) desiredMethod(tool *config.ToolDefinition, desired config.DesiredTool, methods []string) string {
+	if desired.Method != "" {
+		if m.IsMethodAvailable(tool, desired.Method) {
+			return desired.Method
+		}
+		return ""
+	}
+	for _, method := range methods {
+		if m.IsMethodAvailable(tool, method) {
+			return method
+		}
+	}
+	method, _ := m.GetBestInstallMethod(tool)
+	return method
+}
+
+// Apply converges the installed tools to the desired state, running the actions with the
+// scheduler so that dependencies come first
+func (m *Manager) Apply(ctx context.Context, drifts []*Drift) map[string]*ApplyResult {
+	results := make(map[string]*ApplyResult)
+	var mu sync.Mutex
+
+	var tools []config.ToolDefinition
+	byKey := make(map[string]*Drift)
+	for _, d := range drifts {
+		if d.Action == "" {
+			continue
+		}
+		if d.Error != nil {
+			results[d.Tool.Key] = &ApplyResult{Action: d.Action, Error: d.Error}
+			continue
+		}
+		tools = append(tools, *d.Tool)
+		byKey[d.Tool.Key] = d
+	}
+
+	methodFor := func(tool *config.ToolDefinition) string {
+		return byKey[tool.Key].Method
+	}
+
+	ctx = WithOperation(ctx, "apply")
+	skipped := m.runScheduled(ctx, tools, methodFor, func(ctx context.Context, tool *config.ToolDefinition) error {
+		result := m.applyDrift(ctx, tool, byKey[tool.Key])
+		mu.Lock()
+		results[tool.Key] = result
+		mu.Unlock()
+		return result.Error
+	})
+
+	for key, err := range skipped {
+		if ctx.Err() != nil && err == ctx.Err() {
+			continue // Not started because the run was cancelled
+		}
+		results[key] = &ApplyResult{Action: byKey[key].Action, Error: err}
+	}
+	return results
+}
+
+func (m *Manager) applyDrift(ctx context.Context, tool *config.ToolDefinition, d *Drift) *ApplyResult {
+	result := &ApplyResult{Action: d.Action}
+
+	switch d.Action {
+	case "install":
+		var r *InstallResult
+		if tool.Version != "" {
+			r = m.InstallVersion(ctx, tool, d.Method, d.TargetVer)
+		} else {
+			r = m.InstallWithMethod(ctx, tool, d.Method, tool.Install[m.platform.GetOSKey()].ForMethod(d.Method))
+		}
+		result.Success, result.Output, result.Log, result.Error = r.Success, r.Output, r.Log, r.Error
+	case "update":
+		r := m.UpdateToVersion(ctx, tool, d.TargetVer)
+		result.Success, result.Output, result.Log, result.Error = r.Success, r.Output, r.Log, r.Error
+	case "uninstall":
+		r := m.Uninstall(ctx, tool)
+		result.Success, result.Output, result.Log, result.Error = r.Success, r.Output, r.Log, r.Error
+	}
+	return result
+}
@@ -0,0 +1,382 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+)
+
+const (
+	defaultNpmRegistry = "https://registry.npmjs.org/"
+	defaultPyPIIndex   = "https://pypi.org/simple"
+	defaultGitHubAPI   = "https://api.github.com"
+)
+
+// githubAPIBase returns the GitHub API base URL of a version source. The registry
+// override points at a GitHub Enterprise API, e.g. https://github.example.com/api/v3.
+func (c *VersionChecker) githubAPIBase(source config.VersionSource) string {
+	return c.endpoint(source, "github", defaultGitHubAPI)
+}
+
+// newGitHubRequest creates an authenticated GitHub API request when a token is available
+func (c *VersionChecker) newGitHubRequest(ctx context.Context, rawURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if token := c.githubToken(ctx, req.URL.Hostname()); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// githubTokens caches resolved GitHub tokens per host, so `gh` runs at most once per host
+type githubTokens struct {
+	mu     sync.Mutex
+	byHost map[string]string
+}
+
+// githubToken resolves a token for a GitHub host from the environment or the GitHub CLI.
+// `gh` runs with the context of the lookup, so it cannot outlast the lookup's timeout.
+func (c *VersionChecker) githubToken(ctx context.Context, host string) string {
+	for _, name := range githubTokenVariables(host) {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+
+	// The GitHub CLI stores tokens by web host, not by API host, and only has tokens for
+	// the hosts it was logged in to
+	host = strings.TrimPrefix(host, "api.")
+
+	c.tokens.mu.Lock()
+	defer c.tokens.mu.Unlock()
+	if token, ok := c.tokens.byHost[host]; ok {
+		return token
+	}
+
+	token := ""
+	// Without the GitHub CLI the command fails like any other, without a token
+	if githubHostName.MatchString(host) {
+		var out bytes.Buffer
+		err := c.Runner.Run(ctx, &platform.Command{Line: "gh auth token --hostname " + host, Stdout: &out})
+		if ctx.Err() != nil {
+			// Not cached, a later lookup with time left may still get the token
+			return ""
+		}
+		if err == nil {
+			token = strings.TrimSpace(out.String())
+		}
+	}
+	if c.tokens.byHost == nil {
+		c.tokens.byHost = make(map[string]string)
+	}
+	c.tokens.byHost[host] = token
+	return token
+}
+
+// githubHostName matches host names that are safe to put on a command line
+var githubHostName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.-]*$`)
+
+// githubTokenVariables lists the environment variables holding the token for a host,
+// following the GitHub CLI: GITHUB_TOKEN and GH_TOKEN for github.com, GH_ENTERPRISE_TOKEN
+// and GITHUB_ENTERPRISE_TOKEN for the Enterprise host set with GH_HOST. Other hosts get
+// none, so that a registry in a project or the catalog cannot collect the tokens.
+func githubTokenVariables(host string) []string {
+	host = strings.ToLower(host)
+	if host == "github.com" || host == "api.github.com" {
+		return []string{"GITHUB_TOKEN", "GH_TOKEN"}
+	}
+	enterprise := strings.ToLower(os.Getenv("GH_HOST"))
+	if enterprise != "" && strings.TrimPrefix(host, "api.") == strings.TrimPrefix(enterprise, "api.") {
+		return []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	return nil
+}
+
+// npmRegistry returns the registry for an npm package: the source's registry override,
+// then the configured npm endpoint, then npm_config_registry, then the scoped or default
+// registry of the project and user .npmrc files
+func (c *VersionChecker) npmRegistry(source config.VersionSource) string {
+	if source.Registry != "" {
+		return withTrailingSlash(source.Registry)
+	}
+	if registry := c.baseURL("npm"); registry != "" {
+		return withTrailingSlash(registry)
+	}
+	if registry := os.Getenv("npm_config_registry"); registry != "" {
+		return withTrailingSlash(registry)
+	}
+	if registry := os.Getenv("NPM_CONFIG_REGISTRY"); registry != "" {
+		return withTrailingSlash(registry)
+	}
+
+	settings := readNpmrc()
+	if strings.HasPrefix(source.Package, "@") {
+		scope := strings.SplitN(source.Package, "/", 2)[0]
+		if registry := settings[scope+":registry"]; registry != "" {
+			return withTrailingSlash(registry)
+		}
+	}
+	if registry := settings["registry"]; registry != "" {
+		return withTrailingSlash(registry)
+	}
+	return defaultNpmRegistry
+}
+
+// newNpmRequest creates a request for a package document, authenticated with the
+// _authToken configured for the registry in .npmrc
+func newNpmRequest(ctx context.Context, registry, packageName string) (*http.Request, error) {
+	// Scoped packages must have their slash encoded for most registries
+	req, err := http.NewRequestWithContext(ctx, "GET", registry+strings.Replace(packageName, "/", "%2f", 1), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := npmAuthToken(registry); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// npmAuthToken returns the _authToken of the longest .npmrc entry matching the registry.
+// Entries are keyed without the scheme, e.g. //npm.example.com/api/npm/npm-remote/:_authToken.
+func npmAuthToken(registry string) string {
+	u, err := url.Parse(registry)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	target := "//" + u.Host + u.Path
+	if u.Path == "" {
+		target += "/"
+	}
+
+	token, longest := "", 0
+	for key, value := range readNpmrc() {
+		prefix, ok := strings.CutSuffix(key, ":_authToken")
+		if !ok || !npmrcKeyMatches(prefix, target) || len(prefix) <= longest {
+			continue
+		}
+		token, longest = value, len(prefix)
+	}
+	return token
+}
+
+// npmrcKeyMatches reports whether an .npmrc key applies to a registry URL without its
+// scheme. As in npm, the key must cover the whole host and end at a path boundary, so
+// //npm.example.com does not match //npm.example.com.evil.io/.
+func npmrcKeyMatches(prefix, target string) bool {
+	if !strings.HasPrefix(prefix, "//") || !strings.HasPrefix(target, prefix) {
+		return false
+	}
+	return len(target) == len(prefix) || strings.HasSuffix(prefix, "/") || target[len(prefix)] == '/'
+}
+
+// readNpmrc reads the user and project .npmrc files; project settings take precedence
+func readNpmrc() map[string]string {
+	settings := make(map[string]string)
+
+	userConfig := os.Getenv("NPM_CONFIG_USERCONFIG")
+	if userConfig == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			userConfig = filepath.Join(home, ".npmrc")
+		}
+	}
+	for _, path := range []string{userConfig, ".npmrc"} {
+		if path == "" {
+			continue
+		}
+		for key, value := range readIniSection(path, "") {
+			settings[key] = value
+		}
+	}
+	return settings
+}
+
+// pypiIndex returns the package index for a PyPI package: the source's registry override,
+// then the configured pypi endpoint, then PIP_INDEX_URL, then index-url from the first pip
+// configuration file that sets it
+func (c *VersionChecker) pypiIndex(source config.VersionSource) string {
+	if source.Registry != "" {
+		return source.Registry
+	}
+	if index := c.baseURL("pypi"); index != "" {
+		return index
+	}
+	if index := os.Getenv("PIP_INDEX_URL"); index != "" {
+		return index
+	}
+	for _, path := range pipConfigFiles() {
+		settings := readIniSection(path, "global")
+		if index := settings["index-url"]; index != "" {
+			return index
+		}
+		if index := settings["index_url"]; index != "" {
+			return index
+		}
+	}
+	return defaultPyPIIndex
+}
+
+// newPyPIRequest creates a request for the JSON API of a package. The JSON API lives next
+// to the simple index (…/simple → …/pypi/<package>/json), which holds for pypi.org,
+// devpi and Artifactory. Credentials in the index URL are sent as basic auth.
+func newPyPIRequest(ctx context.Context, index, packageName string) (*http.Request, error) {
+	u, err := url.Parse(index)
+	if err != nil {
+		return nil, err
+	}
+	user := u.User
+	u.User = nil
+	u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/simple") + "/pypi/" + packageName + "/json"
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	}
+	return req, nil
+}
+
+// pipConfigFiles lists pip configuration files, most specific first
+func pipConfigFiles() []string {
+	var files []string
+	if path := os.Getenv("PIP_CONFIG_FILE"); path != "" {
+		files = append(files, path)
+	}
+	home, _ := os.UserHomeDir()
+
+	switch {
+	case platform.IsWindows():
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			files = append(files, filepath.Join(appData, "pip", "pip.ini"))
+		}
+		files = append(files, filepath.Join(home, "pip", "pip.ini"))
+		if programData := os.Getenv("ProgramData"); programData != "" {
+			files = append(files, filepath.Join(programData, "pip", "pip.ini"))
+		}
+	default:
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = filepath.Join(home, ".config")
+		}
+		files = append(files, filepath.Join(configHome, "pip", "pip.conf"))
+		if platform.IsDarwin() {
+			files = append(files, filepath.Join(home, "Library", "Application Support", "pip", "pip.conf"))
+		}
+		files = append(files, filepath.Join(home, ".pip", "pip.conf"), "/etc/xdg/pip/pip.conf", "/etc/pip.conf")
+	}
+	return files
+}
+
+// readIniSection reads the key=value pairs of one section of an ini style file. An empty
+// section reads the keys before the first section header, which is all .npmrc has.
+// ${VAR} references in values are expanded.
+func readIniSection(path, section string) map[string]string {
+	settings := make(map[string]string)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return settings
+	}
+	defer file.Close()
+
+	current := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if current != section {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		settings[strings.TrimSpace(key)] = os.ExpandEnv(value)
+	}
+	return settings
+}
+
+// registryArgs returns the flag that points an install command at the tool's registry
+// override, or "" if the tool uses the package manager's own configuration
+func registryArgs(tool *config.ToolDefinition, method string) string {
+	registry := tool.VersionSource.Registry
+	if registry == "" {
+		return ""
+	}
+	switch {
+	case method == "npm" && tool.VersionSource.Type == "npm":
+		return "--registry=" + withTrailingSlash(registry)
+	case method == "pip" && tool.VersionSource.Type == "pypi":
+		return "--index-url " + registry
+	default:
+		return ""
+	}
+}
+
+// registryEnv returns the environment that points the package manager of an install
+// command at the tool's registry override, including npm or pip run by the command in
+// turn, e.g. by a chained command or a postinstall script
+func registryEnv(tool *config.ToolDefinition, method string) []string {
+	registry := tool.VersionSource.Registry
+	switch {
+	case registry == "":
+		return nil
+	case method == "npm" && tool.VersionSource.Type == "npm":
+		return []string{"npm_config_registry=" + withTrailingSlash(registry)}
+	case method == "pip" && tool.VersionSource.Type == "pypi":
+		return []string{"PIP_INDEX_URL=" + registry}
+	default:
+		return nil
+	}
+}
+
+// applyRegistry adds the tool's registry override to the package manager invocation of
+// an install command; other segments of a chained command are left untouched
+func applyRegistry(tool *config.ToolDefinition, method, command string) string {
+	args := registryArgs(tool, method)
+	if args == "" {
+		return command
+	}
+
+	first, rest := command, ""
+	if idx := strings.Index(command, "&&"); idx >= 0 {
+		first, rest = command[:idx], command[idx:]
+	}
+	first = strings.TrimSpace(first)
+	if strings.Contains(first, "--registry") || strings.Contains(first, "--index-url") || strings.Contains(first, " -i ") {
+		return command
+	}
+
+	if rest != "" {
+		return first + " " + args + " " + rest
+	}
+	return first + " " + args
+}
+
+func withTrailingSlash(s string) string {
+	if strings.HasSuffix(s, "/") {
+		return s
+	}
+	return s + "/"
+}
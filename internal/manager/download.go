@@ -0,0 +1,361 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+	"github.com/jschneider/agenthelper/internal/ui"
+)
+
+// scriptAllowed reports whether the unverified script method may be used
+func (m *Manager) scriptAllowed() bool {
+	return !m.opts.VerifiedOnly
+}
+
+// errScriptDisabled is returned for script installs while only verified downloads are allowed
+var errScriptDisabled = fmt.Errorf("script installs are disabled by require_verified_downloads; use a download install with a checksum")
+
+// fetchDownload downloads the file of a download command, verifies it and asks for
+// confirmation. It returns the command with the URL replaced by the file, and a function
+// removing the file.
+func (m *Manager) fetchDownload(ctx context.Context, tool *config.ToolDefinition, spec config.DownloadSpec, command string) (string, func(), error) {
+	rawURL, version, ok := matchDownloadURL(spec.URL, command)
+	if !ok {
+		return "", nil, fmt.Errorf("download URL %s not found in %q", spec.URL, command)
+	}
+	if spec.SHA256 == "" && spec.ChecksumURL == "" {
+		return "", nil, fmt.Errorf("refusing to run an unverified download: %s defines neither sha256 nor checksum_url", tool.Name)
+	}
+	if sig := spec.Signature.Type; sig != "" {
+		if _, err := m.sys.Path.LookPath(sig); err != nil {
+			return "", nil, fmt.Errorf("%s is needed to verify the signature of the download of %s", sig, tool.Name)
+		}
+	}
+	templated := func(s string) string {
+		return strings.ReplaceAll(s, VersionPlaceholder, version)
+	}
+
+	dir, err := os.MkdirTemp("", "agenthelper-download-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	fail := func(err error) (string, func(), error) {
+		cleanup()
+		return "", nil, err
+	}
+
+	ui.Info("Downloading %s...", rawURL)
+	file := filepath.Join(dir, downloadName(rawURL, "download"))
+	if err := m.versions.downloadURL(ctx, rawURL, file, maxDownloadSize); err != nil {
+		return fail(err)
+	}
+
+	sum, err := fileSHA256(file)
+	if err != nil {
+		return fail(err)
+	}
+	want := spec.SHA256
+	checksums := ""
+	if want == "" {
+		checksums = filepath.Join(dir, "checksums-"+downloadName(templated(spec.ChecksumURL), "sums"))
+		if err := m.versions.downloadURL(ctx, templated(spec.ChecksumURL), checksums, maxChecksumSize); err != nil {
+			return fail(err)
+		}
+		if want, err = checksumFor(checksums, filepath.Base(file)); err != nil {
+			return fail(err)
+		}
+	}
+	if !strings.EqualFold(sum, want) {
+		return fail(fmt.Errorf("checksum mismatch for %s: got %s, want %s", rawURL, sum, want))
+	}
+
+	if sig := spec.Signature; sig.Type != "" {
+		signed := file
+		if sig.Of == "checksums" {
+			if checksums == "" {
+				return fail(fmt.Errorf("signature of checksums requires checksum_url"))
+			}
+			signed = checksums
+		}
+		signature := filepath.Join(dir, "signature-"+downloadName(templated(sig.URL), "sig"))
+		if err := m.versions.downloadURL(ctx, templated(sig.URL), signature, maxChecksumSize); err != nil {
+			return fail(err)
+		}
+		if err := m.verifySignature(ctx, sig, signed, signature, dir); err != nil {
+			return fail(err)
+		}
+	}
+
+	local := strings.Replace(command, rawURL, m.sys.QuoteArg(file), 1)
+	if err := m.confirmDownload(tool, rawURL, file, sum, local); err != nil {
+		return fail(err)
+	}
+	return local, cleanup, nil
+}
+
+// matchDownloadURL finds the download URL, with its {{version}} templated, in a command and
+// returns it with the version it was templated with
+func matchDownloadURL(template, command string) (string, string, bool) {
+	if template == "" {
+		return "", "", false
+	}
+	parts := strings.Split(template, VersionPlaceholder)
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	match := regexp.MustCompile(strings.Join(parts, `([^\s'"/]+)`)).FindStringSubmatch(command)
+	if match == nil {
+		return "", "", false
+	}
+	version := ""
+	if len(match) > 1 {
+		version = match[1]
+	}
+	return match[0], version, true
+}
+
+// downloadName returns the file name of a URL, or the fallback if it has none
+func downloadName(rawURL, fallback string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fallback
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" || strings.HasPrefix(name, ".") {
+		return fallback
+	}
+	return name
+}
+
+// Limits of a single download
+const (
+	downloadTimeout = 10 * time.Minute
+	maxDownloadSize = 2 << 30 // packages and AppImages of editors are a few hundred MB
+	maxChecksumSize = 1 << 20 // checksum and signature files
+)
+
+// downloadURL saves the file at a URL without credentials
+func (c *VersionChecker) downloadURL(ctx context.Context, rawURL, path string, limit int64) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return err
+	}
+	return c.download(req, path, limit)
+}
+
+// download saves the response to a request, failing for files larger than limit bytes and
+// downloads taking longer than downloadTimeout
+func (c *VersionChecker) download(req *http.Request, path string, limit int64) error {
+	if c.offline() {
+		return ErrOffline
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), downloadTimeout)
+	defer cancel()
+	fail := func(err error) error {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", downloadTimeout)
+		}
+		return fmt.Errorf("failed to download %s: %w", req.URL, err)
+	}
+
+	resp, err := c.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: status %d", req.URL, resp.StatusCode)
+	}
+	if resp.ContentLength > limit {
+		return fmt.Errorf("failed to download %s: %d bytes exceed the limit of %d", req.URL, resp.ContentLength, limit)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(file, io.LimitReader(resp.Body, limit+1))
+	if err == nil && n > limit {
+		err = fmt.Errorf("larger than the limit of %d bytes", limit)
+	}
+	if err != nil {
+		file.Close()
+		return fail(err)
+	}
+	return file.Close()
+}
+
+// fileSHA256 returns the hex encoded SHA-256 of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checksumFor returns the checksum of a file name from a checksum file in the format of
+// sha256sum, "<sha256>  <name>" or "<sha256> *<name>" per line. A file with a single bare
+// checksum, such as tool.tar.gz.sha256, applies to any name, and so does the sha256hash
+// of a JSON document like the answer of the VS Code update API.
+func checksumFor(checksums, name string) (string, error) {
+	data, err := os.ReadFile(checksums)
+	if err != nil {
+		return "", err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var doc struct {
+			SHA256Hash string `json:"sha256hash"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil || doc.SHA256Hash == "" {
+			return "", fmt.Errorf("checksum document has no sha256hash")
+		}
+		return doc.SHA256Hash, nil
+	}
+
+	var lines [][]string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	for _, fields := range lines {
+		if len(fields) >= 2 && path.Base(strings.TrimPrefix(fields[1], "*")) == name {
+			return fields[0], nil
+		}
+	}
+	if len(lines) == 1 && len(lines[0]) == 1 {
+		return lines[0][0], nil
+	}
+	return "", fmt.Errorf("checksum file has no entry for %s", name)
+}
+
+// verifySignature checks a detached signature with the tool of its type, which fetchDownload
+// made sure is installed
+func (m *Manager) verifySignature(ctx context.Context, sig config.SignatureSpec, file, signature, dir string) error {
+	q := m.sys.QuoteArg
+	var line string
+	switch sig.Type {
+	case "minisign":
+		line = fmt.Sprintf("minisign -V -P %s -m %s -x %s", q(sig.PublicKey), q(file), q(signature))
+	case "cosign":
+		key, err := expandPath(sig.PublicKey)
+		if err != nil {
+			return err
+		}
+		line = fmt.Sprintf("cosign verify-blob --key %s --signature %s %s", q(key), q(signature), q(file))
+	case "gpg":
+		// A keyring of its own, so that only the configured key is trusted
+		key, err := expandPath(sig.PublicKey)
+		if err != nil {
+			return err
+		}
+		keyring := q(filepath.Join(dir, "keyring.gpg"))
+		line = fmt.Sprintf("gpg --batch --no-default-keyring --keyring %s --import %s && gpg --batch --no-default-keyring --keyring %s --verify %s %s",
+			keyring, q(key), keyring, q(signature), q(file))
+	default:
+		return fmt.Errorf("unknown signature type %q", sig.Type)
+	}
+
+	var output bytes.Buffer
+	if err := m.sys.Runner.Run(ctx, &platform.Command{Line: line, Stdout: &output, Stderr: &output}); err != nil {
+		return fmt.Errorf("%s signature of %s is invalid: %w\n%s", sig.Type, filepath.Base(file), err, output.String())
+	}
+	return nil
+}
+
+// maxShownSize is how much of a downloaded script is shown for confirmation
+const maxShownSize = 64 << 10
+
+// readHead reads up to limit bytes of a file, cut after the last complete line when the
+// file is longer, and returns them with the size of the file
+func readHead(path string, limit int) ([]byte, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	head, err := io.ReadAll(io.LimitReader(f, int64(limit)))
+	if err != nil {
+		return nil, 0, err
+	}
+	if int64(len(head)) < info.Size() {
+		if idx := bytes.LastIndexByte(head, '\n'); idx >= 0 {
+			head = head[:idx+1]
+		}
+	}
+	return head, info.Size(), nil
+}
+
+// confirmDownload shows a verified download, its contents if it is a script, and the
+// command that will run it, and asks whether to go ahead
+func (m *Manager) confirmDownload(tool *config.ToolDefinition, rawURL, file, sum, command string) error {
+	if m.opts.AssumeYes {
+		return nil
+	}
+	if !m.opts.Interactive {
+		return fmt.Errorf("refusing to run the download of %s without confirmation; pass --yes to run verified downloads unattended", tool.Name)
+	}
+
+	m.confirm.Lock()
+	defer m.confirm.Unlock()
+
+	head, size, err := readHead(file, maxShownSize)
+	if err != nil {
+		return err
+	}
+	ui.Success("Verified %s (sha256 %s)", rawURL, sum)
+	name := filepath.Base(file)
+	if utf8.Valid(head) && !bytes.ContainsRune(head, 0) {
+		fmt.Println(ui.Bold("--- " + name + " ---"))
+		fmt.Print(string(head))
+		if !bytes.HasSuffix(head, []byte("\n")) {
+			fmt.Println()
+		}
+		if rest := size - int64(len(head)); rest > 0 {
+			fmt.Println(ui.Bold(fmt.Sprintf("--- %d more bytes not shown, see %s ---", rest, file)))
+		} else {
+			fmt.Println(ui.Bold("--- end of " + name + " ---"))
+		}
+	} else {
+		ui.Info("%s is a binary file of %d bytes", name, size)
+	}
+
+	if !ui.PromptConfirm(fmt.Sprintf("Run %s?", command)) {
+		return fmt.Errorf("download of %s not confirmed", tool.Name)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+	"github.com/jschneider/agenthelper/internal/platform/platformtest"
+)
+
+func TestDownloadInstall(t *testing.T) {
+	const checksum = "7443f93f3faab0fd17e46a52fb42536202b210749666d5c21beb5152651e3121"
+
+	tests := []struct {
+		name        string
+		download    config.DownloadSpec
+		assumeYes   bool
+		minisign    int // exit code of minisign, -1 when it is not installed
+		wantRun     bool
+		wantErr     string
+		wantVerify  string
+		wantRequest int
+	}{
+		{"checksum file", config.DownloadSpec{ChecksumURL: "{{server}}/downloads/SHA256SUMS"}, true, -1, true, "", "", 2},
+		{"inline checksum", config.DownloadSpec{SHA256: checksum}, true, -1, true, "", "", 1},
+		{"checksum mismatch", config.DownloadSpec{SHA256: strings.Repeat("0", 64)}, true, -1, false, "checksum mismatch", "", 1},
+		{"no checksum", config.DownloadSpec{}, true, -1, false, "unverified download", "", 0},
+		{"not confirmed", config.DownloadSpec{SHA256: checksum}, false, -1, false, "pass --yes", "", 1},
+		{"valid signature", config.DownloadSpec{SHA256: checksum, Signature: config.SignatureSpec{Type: "minisign", URL: "{{server}}/downloads/install-{{version}}.sh.minisig", PublicKey: "RWQkey"}}, true, 0, true, "", "minisign -V -P 'RWQkey' -m", 2},
+		{"invalid signature", config.DownloadSpec{SHA256: checksum, Signature: config.SignatureSpec{Type: "minisign", URL: "{{server}}/downloads/install-{{version}}.sh.minisig", PublicKey: "RWQkey"}}, true, 1, false, "signature of install-2.3.0.sh is invalid", "minisign -V", 2},
+		{"verifier missing", config.DownloadSpec{SHA256: checksum, Signature: config.SignatureSpec{Type: "minisign", URL: "{{server}}/downloads/install-{{version}}.sh.minisig", PublicKey: "RWQkey"}}, true, -1, false, "minisign is needed", "", 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var executables []string
+			if tt.minisign >= 0 {
+				executables = append(executables, "minisign")
+			}
+			m, runner := newTestManager(t, platform.Linux, executables...)
+			server := newFixtureServer(t)
+			m.versions, _ = newTestChecker(t, server)
+			m.opts.AssumeYes = tt.assumeYes
+
+			download := tt.download
+			download.URL = server.URL + "/downloads/install-{{version}}.sh"
+			download.ChecksumURL = strings.ReplaceAll(download.ChecksumURL, "{{server}}", server.URL)
+			download.Signature.URL = strings.ReplaceAll(download.Signature.URL, "{{server}}", server.URL)
+			download.Run = "sh {{file}} --install"
+			tool := testTool("tool", map[string]config.InstallSpec{"linux": {Download: download}})
+
+			if tt.minisign >= 0 {
+				runner.On("minisign", platformtest.Response{ExitCode: tt.minisign})
+			}
+			runner.On("sh ", platformtest.Response{})
+			runner.On("tool --version", platformtest.Response{Stdout: "2.3.0\n"})
+
+			result := m.InstallVersion(context.Background(), &tool, "download", "2.3.0")
+
+			var ran, verified []string
+			for _, call := range runner.Calls() {
+				if strings.HasPrefix(call, "sh ") {
+					ran = append(ran, call)
+				}
+				if strings.HasPrefix(call, "minisign") {
+					verified = append(verified, call)
+				}
+			}
+			if tt.wantErr != "" {
+				if result.Error == nil || !strings.Contains(result.Error.Error(), tt.wantErr) {
+					t.Errorf("InstallVersion() error = %v, want %q", result.Error, tt.wantErr)
+				}
+			} else if !result.Success {
+				t.Errorf("InstallVersion() failed: %v", result.Error)
+			}
+			if tt.wantRun != (len(ran) == 1) {
+				t.Errorf("ran %q, want run %v", ran, tt.wantRun)
+			}
+			if len(ran) == 1 && (strings.Contains(ran[0], server.URL) || !strings.Contains(ran[0], "install-2.3.0.sh' --install")) {
+				t.Errorf("ran %q, want the downloaded file", ran[0])
+			}
+			if tt.wantVerify != "" && (len(verified) != 1 || !strings.HasPrefix(verified[0], tt.wantVerify)) {
+				t.Errorf("verified with %q, want %q", verified, tt.wantVerify)
+			}
+
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			if len(server.requests) != tt.wantRequest {
+				t.Errorf("%d requests, want %d", len(server.requests), tt.wantRequest)
+			}
+			for _, r := range server.requests {
+				if r.Header.Get("Authorization") != "" {
+					t.Errorf("download of %s sent credentials", r.URL)
+				}
+			}
+		})
+	}
+}
+
+func TestBuiltInDownloadInstall(t *testing.T) {
+	setTools(t)
+	if err := config.LoadToolDefinitions(""); err != nil {
+		t.Fatal(err)
+	}
+	builtIn, ok := config.GetTool("vscode-insiders")
+	if !ok {
+		t.Fatal("vscode-insiders is not a built-in tool")
+	}
+	tool := *builtIn
+
+	m, runner := newTestManager(t, platform.Linux)
+	server := newFixtureServer(t)
+	m.versions, _ = newTestChecker(t, server)
+	// The download and checksum URLs of the definition are served by the fixture server
+	target, _ := url.Parse(server.URL)
+	m.versions.Client = &http.Client{Transport: redirectTransport{target: target, next: server.Client().Transport}}
+	m.opts.AssumeYes = true
+
+	runner.On("sudo dpkg -i", platformtest.Response{})
+	runner.On("code-insiders --version", platformtest.Response{Stdout: "1.96.0-insider\n"})
+
+	result := m.Install(context.Background(), &tool)
+	if !result.Success || result.Method != "download" {
+		t.Fatalf("Install() = %+v, want a download install", result)
+	}
+	var ran []string
+	for _, call := range runner.Calls() {
+		if strings.HasPrefix(call, "sudo ") {
+			ran = append(ran, call)
+		}
+	}
+	if len(ran) != 1 || !strings.HasPrefix(ran[0], "sudo dpkg -i '") || strings.Contains(ran[0], "https://") {
+		t.Errorf("ran %q, want dpkg installing the verified file", ran)
+	}
+}
+
+// redirectTransport sends every request to the target server
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (r redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = r.target.Scheme, r.target.Host
+	return r.next.RoundTrip(req)
+}
+
+func TestChecksumFor(t *testing.T) {
+	sums := map[string]string{
+		"SHA256SUMS":      "aaaa  tool.tar.gz\nbbbb *dist/tool.deb\n",
+		"tool.deb.sha256": "cccc\n",
+		"latest.json":     `{"url": "https://example.com/tool.deb", "sha256hash": "dddd"}`,
+		"other.json":      `{"url": "https://example.com/tool.deb"}`,
+	}
+	tests := []struct {
+		file    string
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"SHA256SUMS", "tool.tar.gz", "aaaa", false},
+		{"SHA256SUMS", "tool.deb", "bbbb", false},
+		{"SHA256SUMS", "other.deb", "", true},
+		{"tool.deb.sha256", "tool.deb", "cccc", false},
+		{"latest.json", "tool.deb", "dddd", false},
+		{"other.json", "tool.deb", "", true},
+	}
+
+	dir := t.TempDir()
+	for name, content := range sums {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tt := range tests {
+		got, err := checksumFor(filepath.Join(dir, tt.file), tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("checksumFor(%s, %s) = %q, %v; want %q", tt.file, tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestDownloadLimit(t *testing.T) {
+	server := newFixtureServer(t)
+	checker, _ := newTestChecker(t, server)
+	path := filepath.Join(t.TempDir(), "install.sh")
+
+	if err := checker.downloadURL(context.Background(), server.URL+"/downloads/install-2.3.0.sh", path, 8); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Errorf("downloadURL() error = %v, want the size limit", err)
+	}
+	if err := checker.downloadURL(context.Background(), server.URL+"/downloads/install-2.3.0.sh", path, maxChecksumSize); err != nil {
+		t.Errorf("downloadURL() error = %v", err)
+	}
+}
+
+func TestReadHead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "install.sh")
+	if err := os.WriteFile(path, []byte("echo one\necho two\necho three\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		limit int
+		want  string
+	}{
+		{100, "echo one\necho two\necho three\n"},
+		{20, "echo one\necho two\n"},
+		{5, "echo "},
+	}
+	for _, tt := range tests {
+		head, size, err := readHead(path, tt.limit)
+		if err != nil || string(head) != tt.want || size != 29 {
+			t.Errorf("readHead(%d) = %q, %d, %v; want %q, 29", tt.limit, head, size, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+)
+
+// HistoryEntry records a change of a tool by an install or update
+type HistoryEntry struct {
+	Tool        string    `json:"tool"`
+	Operation   string    `json:"operation"`
+	FromVersion string    `json:"from_version,omitempty"`
+	ToVersion   string    `json:"to_version,omitempty"`
+	Method      string    `json:"method"`
+	Time        time.Time `json:"time"`
+	Success     bool      `json:"success"`
+}
+
+// historyMu serializes appends of concurrent installs
+var historyMu sync.Mutex
+
+// HistoryFile returns the path of the history file
+func HistoryFile() string {
+	paths, err := platform.GetPaths()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(paths.DataDir, "history.jsonl")
+}
+
+// recordHistory appends an entry to the history; failures only cost the entry
+func recordHistory(ctx context.Context, tool *config.ToolDefinition, step, method, from, to string, success bool) {
+	operation, ok := ctx.Value(operationKey{}).(string)
+	if !ok {
+		operation = step
+	}
+	entry := HistoryEntry{
+		Tool:        tool.Key,
+		Operation:   operation,
+		FromVersion: from,
+		ToVersion:   to,
+		Method:      method,
+		Time:        time.Now(),
+		Success:     success,
+	}
+
+	path := HistoryFile()
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(data, '\n'))
+}
+
+// ReadHistory returns the recorded history, oldest first, optionally of one tool only
+func ReadHistory(tool string) ([]HistoryEntry, error) {
+	path := HistoryFile()
+	if path == "" {
+		return nil, fmt.Errorf("could not determine the history file")
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // Skip a line cut short by a crash
+		}
+		if tool == "" || entry.Tool == tool {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// RollbackTarget returns the change that brought a tool to its installed version, so that
+// its from-version can be reinstalled. Earlier rollbacks are skipped, so that repeated
+// rollbacks keep going back instead of undoing each other.
+func RollbackTarget(tool *config.ToolDefinition, installed string) (*HistoryEntry, error) {
+	entries, err := ReadHistory(tool.Key)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if !e.Success || e.Operation == "rollback" || !SameVersion(e.ToVersion, installed) {
+			continue
+		}
+		if e.FromVersion == "" || SameVersion(e.FromVersion, installed) {
+			continue
+		}
+		return &e, nil
+	}
+	return nil, fmt.Errorf("no recorded change of %s to v%s with a previous version", tool.Name, installed)
+}
+
+// Rollback reinstalls the version a tool had before its last recorded change, using the
+// method of that change
+func (m *Manager) Rollback(ctx context.Context, tool *config.ToolDefinition) *UpdateResult {
+	installed, err := m.GetInstalledVersion(ctx, tool)
+	if err != nil {
+		return &UpdateResult{
+			Success: false,
+			Error:   fmt.Errorf("tool not installed: %w", err),
+		}
+	}
+
+	target, err := RollbackTarget(tool, installed)
+	if err != nil {
+		return &UpdateResult{
+			Success:    false,
+			OldVersion: installed,
+			Error:      err,
+		}
+	}
+
+	result := m.InstallVersion(WithOperation(ctx, "rollback"), tool, target.Method, target.FromVersion)
+	out := &UpdateResult{
+		Success:    result.Success,
+		Method:     result.Method,
+		OldVersion: installed,
+		NewVersion: target.FromVersion,
+		Log:        result.Log,
+		Error:      result.Error,
+	}
+	if out.Success {
+		out.Output = fmt.Sprintf("Rolled back %s from v%s to v%s", tool.Name, installed, target.FromVersion)
+	}
+	return out
+}
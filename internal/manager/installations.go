@@ -0,0 +1,135 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jschneider/agenthelper/internal/config"
+)
+
+// Installation is one copy of a tool's binary on PATH
+type Installation struct {
+	Path     string // as found on PATH
+	Resolved string // with symlinks resolved
+	Version  string // empty when the version command cannot run this copy
+	Method   string // install method that put the copy there, if known
+	Origin   string // method and details such as the nvm node version
+	Active   bool   // first on PATH, so the copy that runs
+	Managed  bool   // the copy agenthelper installed
+}
+
+// InstallationReport lists the copies of a tool on PATH and what is wrong with them
+type InstallationReport struct {
+	Tool          *config.ToolDefinition
+	Installations []Installation
+	Problems      []string
+}
+
+// nodeVersionDir matches the per-version install directories of node version managers
+var nodeVersionDir = regexp.MustCompile(`/\.?(nvm|fnm|volta)/.*/v?(\d+\.\d+\.\d+)/`)
+
+// FindInstallations returns every copy of a tool's binary on PATH, the active one first.
+// Tools run as a subcommand of another binary have none of their own.
+func (m *Manager) FindInstallations(ctx context.Context, tool *config.ToolDefinition) []Installation {
+	fields := strings.Fields(tool.Command)
+	if len(fields) != 1 {
+		return nil
+	}
+
+	managed := ManagedPath(tool)
+	paths := m.sys.Path.FindAll(fields[0])
+	installs := make([]Installation, len(paths))
+	for i, path := range paths {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			resolved = path
+		}
+		method := m.detectMethod(ctx, tool, resolved)
+		installs[i] = Installation{
+			Path:     path,
+			Resolved: resolved,
+			Version:  m.copyVersion(ctx, tool, path),
+			Method:   method,
+			Origin:   origin(method, resolved),
+			Active:   i == 0,
+			Managed:  managed != "" && resolved == managed,
+		}
+	}
+	return installs
+}
+
+// copyVersion runs the version command of a tool against one copy of its binary. It
+// returns "" when the version command does not start with the tool's command.
+func (m *Manager) copyVersion(ctx context.Context, tool *config.ToolDefinition, path string) string {
+	fields := strings.Fields(tool.VersionCmd)
+	if len(fields) == 0 || fields[0] != tool.Command {
+		return ""
+	}
+	t := *tool
+	t.VersionCmd = m.sys.QuoteArg(path) + strings.TrimPrefix(tool.VersionCmd, fields[0])
+	version, _ := m.GetInstalledVersion(ctx, &t)
+	return version
+}
+
+// origin describes where a copy came from
+func origin(method, resolved string) string {
+	if method == "" {
+		method = "unknown"
+	}
+	if match := nodeVersionDir.FindStringSubmatch(strings.ToLower(filepath.ToSlash(resolved))); match != nil {
+		return fmt.Sprintf("%s (%s node v%s)", method, match[1], match[2])
+	}
+	return method
+}
+
+// CheckInstallations reports the tools with more than one copy on PATH, flagging when the
+// copy that runs is not the one agenthelper manages or is older than another copy
+func (m *Manager) CheckInstallations(ctx context.Context) []*InstallationReport {
+	tools := config.GetAllTools()
+	reports := make([]*InstallationReport, len(tools))
+	forEachTool(tools, statusConcurrency, func(idx int, tool *config.ToolDefinition) {
+		installs := m.FindInstallations(ctx, tool)
+		if len(installs) < 2 {
+			return
+		}
+		reports[idx] = &InstallationReport{
+			Tool:          tool,
+			Installations: installs,
+			Problems:      m.installationProblems(installs),
+		}
+	})
+
+	var found []*InstallationReport
+	for _, r := range reports {
+		if r != nil {
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+func (m *Manager) installationProblems(installs []Installation) []string {
+	active := installs[0]
+	problems := []string{fmt.Sprintf("%d copies on PATH", len(installs))}
+
+	for _, inst := range installs[1:] {
+		if inst.Managed {
+			problems = append(problems, fmt.Sprintf("%s shadows the copy agenthelper manages at %s", active.Path, inst.Path))
+		}
+	}
+	if active.Version == "" {
+		return problems
+	}
+	for _, inst := range installs[1:] {
+		if inst.Version == "" {
+			continue
+		}
+		if newer, err := m.CompareVersions(active.Version, inst.Version); err == nil && newer {
+			problems = append(problems, fmt.Sprintf("%s runs v%s, but v%s is installed at %s", active.Path, active.Version, inst.Version, inst.Path))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,116 @@
+package manager
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+	"github.com/jschneider/agenthelper/internal/platform/platformtest"
+)
+
+func TestCheckInstallations(t *testing.T) {
+	const (
+		local = "/usr/local/bin/tool"
+		npm   = "/home/user/.npm-global/lib/node_modules/tool/bin/tool"
+		nvm   = "/home/user/.nvm/versions/node/v20.11.0/bin/tool"
+	)
+
+	tests := []struct {
+		name         string
+		copies       []string          // PATH order
+		versions     map[string]string // version of each copy
+		managed      string            // the copy agenthelper installed, if any
+		wantProblems []string          // nil when no report is expected
+		wantOrigins  []string
+	}{
+		{
+			name:     "single copy",
+			copies:   []string{local},
+			versions: map[string]string{local: "1.0.0"},
+		},
+		{
+			name:         "managed copy shadowed",
+			copies:       []string{local, npm},
+			versions:     map[string]string{local: "2.0.0", npm: "2.0.0"},
+			managed:      npm,
+			wantProblems: []string{"2 copies on PATH", local + " shadows the copy agenthelper manages at " + npm},
+			wantOrigins:  []string{"unknown", "npm"},
+		},
+		{
+			name:         "older copy runs",
+			copies:       []string{nvm, npm},
+			versions:     map[string]string{nvm: "1.0.0", npm: "2.0.0"},
+			wantProblems: []string{"2 copies on PATH", nvm + " runs v1.0.0, but v2.0.0 is installed at " + npm},
+			wantOrigins:  []string{"unknown (nvm node v20.11.0)", "npm"},
+		},
+		{
+			name:         "newer copy runs",
+			copies:       []string{npm, local},
+			versions:     map[string]string{npm: "2.0.0", local: "1.0.0"},
+			wantProblems: []string{"2 copies on PATH"},
+			wantOrigins:  []string{"npm", "unknown"},
+		},
+		{
+			name:         "version of the active copy unknown",
+			copies:       []string{local, npm},
+			versions:     map[string]string{npm: "2.0.0"},
+			wantProblems: []string{"2 copies on PATH"},
+			wantOrigins:  []string{"unknown", "npm"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			m, runner := newTestManager(t, platform.Linux)
+			m.sys.Path.(platformtest.Path)["tool"] = tt.copies
+			tool := testTool("tool", nil)
+			setTools(t, tool)
+			for path, version := range tt.versions {
+				runner.On(m.sys.QuoteArg(path)+" --version", platformtest.Response{Stdout: version + "\n"})
+			}
+			if tt.managed != "" {
+				saveProvenance(tool.Key, &Provenance{Method: "npm", Path: tt.managed, Source: "install"})
+			}
+
+			reports := m.CheckInstallations(context.Background())
+			if tt.wantProblems == nil {
+				if len(reports) != 0 {
+					t.Errorf("CheckInstallations() = %d reports, want none", len(reports))
+				}
+				return
+			}
+			if len(reports) != 1 {
+				t.Fatalf("CheckInstallations() = %d reports, want 1", len(reports))
+			}
+			report := reports[0]
+			if !reflect.DeepEqual(report.Problems, tt.wantProblems) {
+				t.Errorf("problems = %q, want %q", report.Problems, tt.wantProblems)
+			}
+
+			var origins []string
+			for i, inst := range report.Installations {
+				origins = append(origins, inst.Origin)
+				if inst.Path != tt.copies[i] || inst.Active != (i == 0) || inst.Managed != (inst.Path == tt.managed) {
+					t.Errorf("installation %d = %+v, want %s", i, inst, tt.copies[i])
+				}
+				if inst.Version != tt.versions[inst.Path] {
+					t.Errorf("version of %s = %q, want %q", inst.Path, inst.Version, tt.versions[inst.Path])
+				}
+			}
+			if !reflect.DeepEqual(origins, tt.wantOrigins) {
+				t.Errorf("origins = %q, want %q", origins, tt.wantOrigins)
+			}
+		})
+	}
+}
+
+func TestFindInstallationsOfSubcommand(t *testing.T) {
+	m, _ := newTestManager(t, platform.Linux, "gh")
+	tool := config.ToolDefinition{Key: "copilot", Command: "gh copilot"}
+	if installs := m.FindInstallations(context.Background(), &tool); installs != nil {
+		t.Errorf("FindInstallations() = %+v, want none for a subcommand", installs)
+	}
+}
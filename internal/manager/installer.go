@@ -1,8 +1,9 @@
 package manager
 
 import (
-	"bytes"
+	"context"
 	"fmt"
+	"sync"
 
 	"github.com/jschneider/agenthelper/internal/config"
 	"github.com/jschneider/agenthelper/internal/platform"
@@ -14,11 +15,15 @@ type InstallResult struct {
 	Success bool
 	Method  string
 	Output  string
+	Log     string // full output of the command that ran
 	Error   error
 }
 
 // Install installs a tool using the best available method
-func (m *Manager) Install(tool *config.ToolDefinition) *InstallResult {
+func (m *Manager) Install(ctx context.Context, tool *config.ToolDefinition) *InstallResult {
+	if m.useMirror() {
+		return m.installFromMirror(ctx, tool, "")
+	}
 	method, command := m.GetBestInstallMethod(tool)
 	if method == "" {
 		return &InstallResult{
@@ -27,151 +32,283 @@ func (m *Manager) Install(tool *config.ToolDefinition) *InstallResult {
 		}
 	}
 
-	return m.InstallWithMethod(tool, method, command)
+	return m.InstallWithMethod(ctx, tool, method, command)
 }
 
-// InstallWithMethod installs a tool using a specific method
-func (m *Manager) InstallWithMethod(tool *config.ToolDefinition, method, command string) *InstallResult {
+// InstallWithMethod installs a tool using a specific method. Without a method, e.g. when
+// none is recorded for a tool being repaired, it installs with the best available one.
+func (m *Manager) InstallWithMethod(ctx context.Context, tool *config.ToolDefinition, method, command string) *InstallResult {
+	if method == "" {
+		return m.Install(ctx, tool)
+	}
+	if m.useMirror() {
+		return m.installFromMirror(ctx, tool, method)
+	}
+	command, err := m.resolveInstallCommand(ctx, tool, method, command)
+	if err != nil {
+		return &InstallResult{
+			Success: false,
+			Method:  method,
+			Error:   err,
+		}
+	}
+	return m.runInstall(ctx, tool, method, command)
+}
+
+// resolveInstallCommand templates the version into commands that require one, and pins
+// pre-release channels that the package manager would not install by default
+func (m *Manager) resolveInstallCommand(ctx context.Context, tool *config.ToolDefinition, method, command string) (string, error) {
+	if HasVersionPlaceholder(command) || tracksPrerelease(tool) {
+		version, err := m.versions.LatestAllowedVersion(ctx, tool)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve version for %s: %w", tool.Name, err)
+		}
+		// Methods that cannot be pinned, such as scripts, install whatever they install
+		if pinned, err := PinCommand(method, command, version); err == nil {
+			command = pinned
+		}
+	}
+	return command, nil
+}
+
+// runInstall runs an install command as is and verifies the tool is found afterwards
+func (m *Manager) runInstall(ctx context.Context, tool *config.ToolDefinition, method, command string) *InstallResult {
 	result := &InstallResult{
 		Method: method,
 	}
+	command = applyRegistry(tool, method, command)
+
+	// Remembered for the history, e.g. when installing a specific version over another
+	previous, _ := m.GetInstalledVersion(ctx, tool)
 
 	ui.Info("Installing %s using %s...", tool.Name, method)
 
-	cmd := platform.NewShellCommand(command)
+	if method == "download" {
+		local, cleanup, err := m.fetchDownload(ctx, tool, tool.Install[m.platform.GetOSKey()].Download, command)
+		if err != nil {
+			result.Error = err
+			recordHistory(ctx, tool, "install", method, previous, "", false)
+			return result
+		}
+		defer cleanup()
+		command = local
+	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	timeout := tool.InstallTimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	err := cmd.Run()
-	result.Output = stdout.String()
+	out, err := m.runToolCommand(ctx, tool, "install", method, command)
+	result.Output = out.stdout.String()
 	if result.Output == "" {
-		result.Output = stderr.String()
+		result.Output = out.stderr.String()
 	}
+	result.Log = out.log.String()
 
 	if err != nil {
 		result.Success = false
-		result.Error = fmt.Errorf("installation failed: %w\n%s", err, stderr.String())
+		result.Error = fmt.Errorf("installation failed: %w\n%s", contextError(ctx, timeout, err), out.stderr.String())
+		recordHistory(ctx, tool, "install", method, previous, "", false)
 		return result
 	}
 
 	// Verify installation
-	version, err := m.GetInstalledVersion(tool)
+	version, err := m.GetInstalledVersion(ctx, tool)
 	if err != nil {
 		result.Success = false
 		result.Error = fmt.Errorf("installation completed but tool not found: %w", err)
+		recordHistory(ctx, tool, "install", method, previous, "", false)
 		return result
 	}
 
+	if !SameVersion(previous, version) {
+		recordHistory(ctx, tool, "install", method, previous, version, true)
+	}
+	m.recordProvenance(tool, method)
 	result.Success = true
 	result.Output = fmt.Sprintf("Successfully installed %s version %s", tool.Name, version)
 	return result
 }
 
-// InstallAll installs all tools
-func (m *Manager) InstallAll(preferredMethod string) map[string]*InstallResult {
+// InstallVersion installs an exact version of a tool. The given method is used when it is
+// available on this platform, otherwise the best available method is chosen.
+func (m *Manager) InstallVersion(ctx context.Context, tool *config.ToolDefinition, method, version string) *InstallResult {
+	method, pinned, err := m.versionInstallCommand(tool, method, version)
+	if err != nil {
+		return &InstallResult{
+			Success: false,
+			Method:  method,
+			Error:   err,
+		}
+	}
+
+	result := m.runInstall(ctx, tool, method, pinned)
+	if !result.Success {
+		return result
+	}
+
+	installed, err := m.GetInstalledVersion(ctx, tool)
+	if err == nil && !SameVersion(installed, version) {
+		result.Success = false
+		result.Error = fmt.Errorf("requested %s %s but %s is installed", tool.Name, version, installed)
+	}
+	return result
+}
+
+// versionInstallCommand returns the method and command that install an exact version of
+// a tool, preferring the given method when it is available. Offline with a mirror, only the
+// given method is used.
+func (m *Manager) versionInstallCommand(tool *config.ToolDefinition, method, version string) (string, string, error) {
+	if m.useMirror() {
+		method, command, _, err := m.mirrorInstallCommand(tool, method, version)
+		return method, command, err
+	}
+
+	command := ""
+	if method != "" && m.IsMethodAvailable(tool, method) {
+		command = tool.Install[m.platform.GetOSKey()].ForMethod(method)
+	}
+	if command == "" {
+		method, command = m.GetBestInstallMethod(tool)
+	}
+	if method == "" {
+		return "", "", fmt.Errorf("no installation method available for %s on %s", tool.Name, m.platform.String())
+	}
+
+	pinned, err := PinCommand(method, command, version)
+	if err != nil {
+		return method, "", err
+	}
+	return method, pinned, nil
+}
+
+// InstallAll installs all tools, running up to the configured number of jobs at a time
+// and installing the dependencies of a tool before the tool itself
+func (m *Manager) InstallAll(ctx context.Context, preferredMethod string) map[string]*InstallResult {
+	return m.InstallTools(ctx, config.GetAllTools(), preferredMethod)
+}
+
+// InstallTools installs the given tools with the scheduler. Tools that are already
+// installed are reported as such; a tool whose dependency failed is skipped.
+func (m *Manager) InstallTools(ctx context.Context, tools []config.ToolDefinition, preferredMethod string) map[string]*InstallResult {
 	results := make(map[string]*InstallResult)
-	tools := config.GetAllTools()
+	var mu sync.Mutex
+	osKey := m.platform.GetOSKey()
 
-	for _, tool := range tools {
-		t := tool // Create a copy for the closure
+	methodFor := func(tool *config.ToolDefinition) string {
+		if preferredMethod != "" && tool.Install[osKey].ForMethod(preferredMethod) != "" {
+			return preferredMethod
+		}
+		method, _ := m.GetBestInstallMethod(tool)
+		return method
+	}
 
-		// Check if already installed
-		if _, err := m.GetInstalledVersion(&t); err == nil {
-			results[t.Key] = &InstallResult{
+	skipped := m.runScheduled(ctx, tools, methodFor, func(ctx context.Context, tool *config.ToolDefinition) error {
+		var result *InstallResult
+		if _, err := m.GetInstalledVersion(ctx, tool); err == nil {
+			result = &InstallResult{
 				Success: true,
 				Output:  "Already installed",
 			}
-			continue
+		} else if cmd := tool.Install[osKey].ForMethod(preferredMethod); preferredMethod != "" && cmd != "" {
+			result = m.InstallWithMethod(ctx, tool, preferredMethod, cmd)
+		} else {
+			result = m.Install(ctx, tool)
 		}
 
-		// Install
-		if preferredMethod != "" {
-			osKey := m.platform.GetOSKey()
-			if spec, ok := t.Install[osKey]; ok {
-				var cmd string
-				switch preferredMethod {
-				case "winget":
-					cmd = spec.WinGet
-				case "brew":
-					cmd = spec.Brew
-				case "npm":
-					cmd = spec.Npm
-				case "pip":
-					cmd = spec.Pip
-				case "apt":
-					cmd = spec.Apt
-				}
-				if cmd != "" {
-					results[t.Key] = m.InstallWithMethod(&t, preferredMethod, cmd)
-					continue
-				}
-			}
-		}
+		mu.Lock()
+		results[tool.Key] = result
+		mu.Unlock()
+		return result.Error
+	})
 
-		results[t.Key] = m.Install(&t)
+	for key, err := range skipped {
+		if ctx.Err() != nil && err == ctx.Err() {
+			continue // Not started because the run was cancelled
+		}
+		results[key] = &InstallResult{Success: false, Error: err}
 	}
 
 	return results
 }
 
-// Uninstall removes a tool
-func (m *Manager) Uninstall(tool *config.ToolDefinition) *InstallResult {
-	result := &InstallResult{}
-
+// uninstallCommand returns the method and command that uninstall a tool. An uninstall
+// spec for this platform is used when the tool has one, preferring the method the tool is
+// installed with; otherwise the command is derived from that method's install command.
+func (m *Manager) uninstallCommand(ctx context.Context, tool *config.ToolDefinition) (string, string, error) {
 	osKey := m.platform.GetOSKey()
-	uninstallSpec, ok := tool.Uninstall[osKey]
-	if !ok {
-		return &InstallResult{
-			Success: false,
-			Error:   fmt.Errorf("no uninstall method available for %s on %s", tool.Name, m.platform.String()),
+	installMethod, installCommand := m.InstalledMethod(ctx, tool)
+
+	if uninstallSpec, ok := tool.Uninstall[osKey]; ok {
+		if command := uninstallSpec.ForMethod(installMethod); command != "" {
+			return installMethod, command, nil
 		}
-	}
 
-	// Try uninstall methods in order
-	var command string
-	var method string
+		// Try uninstall methods in order
+		methods := []string{"brew", "npm", "pip", "apt", "pacman", "download", "script"}
+		if m.platform.OS == platform.Windows {
+			methods = append([]string{"winget"}, methods...)
+		}
+		for _, method := range methods {
+			if command := uninstallSpec.ForMethod(method); command != "" {
+				return method, command, nil
+			}
+		}
+	}
 
-	if platform.IsWindows() && uninstallSpec.WinGet != "" {
-		method = "winget"
-		command = uninstallSpec.WinGet
-	} else if uninstallSpec.Brew != "" {
-		method = "brew"
-		command = uninstallSpec.Brew
-	} else if uninstallSpec.Npm != "" {
-		method = "npm"
-		command = uninstallSpec.Npm
-	} else if uninstallSpec.Pip != "" {
-		method = "pip"
-		command = uninstallSpec.Pip
+	if installMethod == "" {
+		return "", "", fmt.Errorf("no uninstall method available for %s on %s", tool.Name, m.platform.String())
+	}
+	command, err := DeriveUninstallCommand(installMethod, installCommand)
+	if err != nil {
+		return installMethod, "", fmt.Errorf("no uninstall command found for %s: %w", tool.Name, err)
 	}
+	return installMethod, command, nil
+}
 
-	if command == "" {
+// Uninstall removes a tool
+func (m *Manager) Uninstall(ctx context.Context, tool *config.ToolDefinition) *InstallResult {
+	result := &InstallResult{}
+
+	method, command, err := m.uninstallCommand(ctx, tool)
+	if err != nil {
 		return &InstallResult{
 			Success: false,
-			Error:   fmt.Errorf("no uninstall command found for %s", tool.Name),
+			Error:   err,
 		}
 	}
 
+	previous, _ := m.GetInstalledVersion(ctx, tool)
+
 	ui.Info("Uninstalling %s using %s...", tool.Name, method)
 
-	cmd := platform.NewShellCommand(command)
+	if method == "download" {
+		local, cleanup, err := m.fetchDownload(ctx, tool, tool.Uninstall[m.platform.GetOSKey()].Download, command)
+		if err != nil {
+			return &InstallResult{Method: method, Error: err}
+		}
+		defer cleanup()
+		command = local
+	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	timeout := tool.InstallTimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	err := cmd.Run()
-	result.Output = stdout.String()
+	out, err := m.runToolCommand(ctx, tool, "uninstall", method, command)
+	result.Output = out.stdout.String()
+	result.Log = out.log.String()
 	result.Method = method
 
 	if err != nil {
 		result.Success = false
-		result.Error = fmt.Errorf("uninstall failed: %w\n%s", err, stderr.String())
+		result.Error = fmt.Errorf("uninstall failed: %w\n%s", contextError(ctx, timeout, err), out.stderr.String())
+		recordHistory(ctx, tool, "uninstall", method, previous, previous, false)
 		return result
 	}
 
+	recordHistory(ctx, tool, "uninstall", method, previous, "", true)
+	saveProvenance(tool.Key, nil)
 	result.Success = true
 	result.Output = fmt.Sprintf("Successfully uninstalled %s", tool.Name)
 	return result
@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// LockfileName is the default file name of the team lockfile
+const LockfileName = "agenthelper.lock"
+
+// lockfileHeader is written at the top of every generated lockfile
+const lockfileHeader = "# Generated by 'agenthelper lock'. Commit this file and run 'agenthelper sync --locked'.\n"
+
+// Lockfile pins exact tool versions and install methods across machines
+type Lockfile struct {
+	Tools []LockedTool `yaml:"tools" json:"tools"`
+}
+
+// LockedTool is a single pinned tool in the lockfile
+type LockedTool struct {
+	Key      string `yaml:"key" json:"key"`
+	Version  string `yaml:"version,omitempty" json:"version,omitempty"`
+	Method   string `yaml:"method,omitempty" json:"method,omitempty"`
+	Platform string `yaml:"platform" json:"platform"`
+}
+
+// GenerateLockfile records the installed version and install method of every configured tool.
+// Tools that are not installed are recorded without a version and are skipped by sync.
+func (m *Manager) GenerateLockfile(ctx context.Context) *Lockfile {
+	tools := config.GetAllTools()
+	lock := &Lockfile{
+		Tools: make([]LockedTool, len(tools)),
+	}
+
+	forEachTool(tools, statusConcurrency, func(idx int, t *config.ToolDefinition) {
+		entry := LockedTool{
+			Key:      t.Key,
+			Platform: m.platform.GetOSKey(),
+		}
+		if version, err := m.GetInstalledVersion(ctx, t); err == nil {
+			entry.Version = version
+			entry.Method, _ = m.InstalledMethod(ctx, t)
+		}
+		lock.Tools[idx] = entry
+	})
+
+	return lock
+}
+
+// Get returns the locked entry for a tool
+func (l *Lockfile) Get(key string) (*LockedTool, bool) {
+	for i := range l.Tools {
+		if l.Tools[i].Key == key {
+			return &l.Tools[i], true
+		}
+	}
+	return nil, false
+}
+
+// ReadLockfile loads a lockfile from disk
+func ReadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+
+	lock := &Lockfile{}
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+
+	return lock, nil
+}
+
+// WriteLockfile writes a lockfile to disk
+func WriteLockfile(path string, lock *Lockfile) error {
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+
+	if err := os.WriteFile(path, append([]byte(lockfileHeader), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+	"github.com/jschneider/agenthelper/internal/ui"
+)
+
+// networkProbeTimeout bounds the network detection, which runs at most once per detector
+const networkProbeTimeout = 2 * time.Second
+
+// ErrOffline is returned for lookups that need the network while offline
+var ErrOffline = errors.New("offline and no cached response")
+
+// OfflineDetector returns the Options.Offline function: always offline when forced with
+// --offline, otherwise detected on first use when none of the default or configured
+// version sources can be reached
+func OfflineDetector(forced bool, versionSources map[string]string) func() bool {
+	if forced {
+		return func() bool { return true }
+	}
+	var once sync.Once
+	var detected bool
+	return func() bool {
+		once.Do(func() {
+			detected = !probeNetwork(probeURLs(versionSources), networkProbeTimeout)
+			if detected {
+				ui.Warn("No network connection, working offline. Pass --offline to skip this check.")
+			}
+		})
+		return detected
+	}
+}
+
+// mirrorDir returns the absolute path of the mirror directory, or "" if none is set
+func (m *Manager) mirrorDir() string {
+	dir := m.opts.Mirror
+	if dir == "" {
+		return ""
+	}
+	if expanded, err := expandPath(dir); err == nil {
+		return expanded
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// useMirror reports whether installs come from the mirror directory. The network is only
+// probed when a mirror is configured.
+func (m *Manager) useMirror() bool {
+	return m.opts.Mirror != "" && m.versions.offline()
+}
+
+// probeURLs returns the endpoints whose reachability decides whether the network is up
+func probeURLs(versionSources map[string]string) []string {
+	urls := []string{defaultNpmRegistry, defaultGitHubAPI, defaultPyPIIndex}
+	for _, baseURL := range versionSources {
+		urls = append(urls, baseURL)
+	}
+	return urls
+}
+
+// probeNetwork reports whether a connection to any of the URLs, or to the proxy configured
+// for it, can be opened within the timeout
+func probeNetwork(urls []string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	addresses := make(map[string]bool)
+	for _, raw := range urls {
+		if address := dialAddress(raw); address != "" {
+			addresses[address] = true
+		}
+	}
+
+	reachable := make(chan bool, len(addresses))
+	var dialer net.Dialer
+	for address := range addresses {
+		go func(address string) {
+			conn, err := dialer.DialContext(ctx, "tcp", address)
+			if err == nil {
+				conn.Close()
+			}
+			reachable <- err == nil
+		}(address)
+	}
+	for range addresses {
+		if <-reachable {
+			return true
+		}
+	}
+	return false
+}
+
+// dialAddress returns the host:port a request to the URL connects to first
+func dialAddress(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	if proxy, err := http.ProxyFromEnvironment(&http.Request{URL: u}); err == nil && proxy != nil {
+		u = proxy
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// mirrorPackage is a pre-downloaded package of a tool in the mirror directory
+type mirrorPackage struct {
+	Method  string // the install method the package is installed with
+	Path    string
+	Version string
+}
+
+// mirrorKinds lists the package kinds of the mirror with the install method of each, in
+// order of preference
+var mirrorKinds = []struct {
+	method  string
+	pattern func(config.MirrorSpec) string
+	linux   bool // only installable on Linux
+}{
+	{"apt", func(s config.MirrorSpec) string { return s.Deb }, true},
+	{"npm", func(s config.MirrorSpec) string { return s.Npm }, false},
+	{"pip", func(s config.MirrorSpec) string { return s.Pip }, false},
+	{"script", func(s config.MirrorSpec) string { return s.AppImage }, true},
+}
+
+// findMirrorPackage returns the newest package of a tool in the mirror directory that can
+// be installed here. With a method only packages of that method are considered, so an
+// update does not install a second copy. With a version only that version is used,
+// otherwise the tool's version constraint applies.
+func (m *Manager) findMirrorPackage(tool *config.ToolDefinition, method, version string) (*mirrorPackage, error) {
+	dir := m.mirrorDir()
+	var constraint *semver.Constraints
+	if tool.Version != "" && version == "" {
+		c, err := semver.NewConstraint(tool.Version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q for %s: %w", tool.Version, tool.Key, err)
+		}
+		constraint = c
+	}
+
+	for _, kind := range mirrorKinds {
+		pattern := kind.pattern(tool.Mirror)
+		if pattern == "" || (method != "" && kind.method != method) {
+			continue
+		}
+		if kind.linux && m.platform.OS != platform.Linux {
+			continue
+		}
+		if kind.method != "script" && !m.hasPackageManager(kind.method) {
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid mirror pattern %q for %s: %w", pattern, tool.Key, err)
+		}
+		byVersion := make(map[string]string)
+		var versions []string
+		for _, path := range matches {
+			v := ExtractVersion(filepath.Base(path), "")
+			if v == "" || (version != "" && !SameVersion(v, version)) || !matchesArch(filepath.Base(path), m.platform.Arch) {
+				continue
+			}
+			if constraint != nil {
+				if sv, err := semver.NewVersion(v); err != nil || !constraint.Check(sv) {
+					continue
+				}
+			}
+			byVersion[v] = path
+			versions = append(versions, v)
+		}
+		if newest := newestVersion(versions); newest != "" {
+			return &mirrorPackage{Method: kind.method, Path: byVersion[newest], Version: newest}, nil
+		}
+	}
+
+	kind := "package"
+	if method != "" {
+		kind = method + " package"
+	}
+	if version != "" {
+		kind += " of version " + version
+	}
+	return nil, fmt.Errorf("mirror %s has no %s of %s that can be installed here", dir, kind, tool.Name)
+}
+
+// mirrorCommand returns the command that installs a mirror package
+func (m *Manager) mirrorCommand(tool *config.ToolDefinition, pkg *mirrorPackage) (string, error) {
+	q := m.sys.QuoteArg
+	switch pkg.Method {
+	case "npm":
+		return "npm install -g --offline " + q(pkg.Path), nil
+	case "pip":
+		// Dependencies are resolved from the wheels next to the package
+		return "pip install --no-index --find-links " + q(filepath.Dir(pkg.Path)) + " " + q(pkg.Path), nil
+	case "apt":
+		return "apt install -y " + q(pkg.Path), nil
+	}
+
+	// AppImages are linked under the tool's command; the link target keeps the .AppImage
+	// suffix, by which the install method is detected later
+	paths, err := platform.GetPaths()
+	if err != nil {
+		return "", err
+	}
+	command := tool.Key
+	if fields := strings.Fields(tool.Command); len(fields) > 0 {
+		command = fields[0]
+	}
+	image := filepath.Join(paths.BinDir, command+".AppImage")
+	return fmt.Sprintf("mkdir -p %s && install -m 755 %s %s && ln -sf %s %s",
+		q(paths.BinDir), q(pkg.Path), q(image), q(command+".AppImage"), q(filepath.Join(paths.BinDir, command))), nil
+}
+
+// mirrorInstallCommand returns the method and command that install a tool from the
+// mirror, and the version of the package
+func (m *Manager) mirrorInstallCommand(tool *config.ToolDefinition, method, version string) (string, string, string, error) {
+	pkg, err := m.findMirrorPackage(tool, method, version)
+	if err != nil {
+		return method, "", "", err
+	}
+	command, err := m.mirrorCommand(tool, pkg)
+	return pkg.Method, command, pkg.Version, err
+}
+
+// installFromMirror installs the newest package of a tool in the mirror, of the given
+// method if one is set
+func (m *Manager) installFromMirror(ctx context.Context, tool *config.ToolDefinition, method string) *InstallResult {
+	method, command, _, err := m.mirrorInstallCommand(tool, method, "")
+	if err != nil {
+		return &InstallResult{
+			Success: false,
+			Method:  method,
+			Error:   err,
+		}
+	}
+	return m.runInstall(ctx, tool, method, command)
+}
@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+)
+
+func TestOfflineVersionLookups(t *testing.T) {
+	server := newFixtureServer(t)
+	checker, _ := newTestChecker(t, server)
+	checker.CacheTTL = 0 // every cached answer is stale
+	cached := sourceTool(config.VersionSource{Type: "github", Owner: "acme", Repo: "tool"})
+	if _, err := checker.LatestVersion(context.Background(), cached); err != nil {
+		t.Fatalf("LatestVersion() failed: %v", err)
+	}
+
+	checker.Offline = func() bool { return true }
+	server.mu.Lock()
+	online := len(server.requests)
+	server.mu.Unlock()
+
+	if latest, err := checker.LatestVersion(context.Background(), cached); err != nil || latest != "2.3.0" {
+		t.Errorf("LatestVersion() = %q, %v; want the cached 2.3.0", latest, err)
+	}
+	uncached := sourceTool(config.VersionSource{Type: "npm", Package: "tool"})
+	if _, err := checker.LatestVersion(context.Background(), uncached); !errors.Is(err, ErrOffline) {
+		t.Errorf("LatestVersion() error = %v, want ErrOffline", err)
+	}
+	command := sourceTool(config.VersionSource{Type: "command", Command: "tool --latest"})
+	if _, err := checker.LatestVersion(context.Background(), command); !errors.Is(err, ErrOffline) {
+		t.Errorf("LatestVersion() error = %v, want ErrOffline for a command source", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.requests) != online {
+		t.Errorf("%d requests reached the server while offline", len(server.requests)-online)
+	}
+}
+
+func TestMirrorInstallPlans(t *testing.T) {
+	mirror := config.MirrorSpec{Npm: "tool-*.tgz", Deb: "tool_*_amd64.deb"}
+	files := []string{"tool-1.0.0.tgz", "tool-1.2.0.tgz", "tool_1.1.0_amd64.deb", "other-9.0.0.tgz"}
+
+	tests := []struct {
+		name        string
+		os          platform.OS
+		executables []string
+		mirror      config.MirrorSpec
+		constraint  string
+		method      string
+		version     string
+		wantMethod  string
+		wantFile    string
+		wantErr     string
+	}{
+		{"newest npm package", platform.Linux, []string{"npm"}, mirror, "", "", "", "npm", "tool-1.2.0.tgz", ""},
+		{"deb preferred with apt", platform.Linux, []string{"apt", "npm"}, mirror, "", "", "", "apt", "tool_1.1.0_amd64.deb", ""},
+		{"deb only on linux", platform.Darwin, []string{"apt", "npm"}, mirror, "", "", "", "npm", "tool-1.2.0.tgz", ""},
+		{"method restricts the kind", platform.Linux, []string{"apt", "npm"}, mirror, "", "npm", "", "npm", "tool-1.2.0.tgz", ""},
+		{"constraint applies", platform.Linux, []string{"npm"}, mirror, "~1.0", "", "", "npm", "tool-1.0.0.tgz", ""},
+		{"exact version", platform.Linux, []string{"npm"}, mirror, "", "", "1.0.0", "npm", "tool-1.0.0.tgz", ""},
+		{"missing version", platform.Linux, []string{"npm"}, mirror, "", "", "2.0.0", "", "", "no package of version 2.0.0"},
+		{"package manager missing", platform.Linux, nil, config.MirrorSpec{Npm: "tool-*.tgz"}, "", "", "", "", "", "no package"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			m, _ := newTestManager(t, tt.os, tt.executables...)
+			m.opts.Mirror = dir
+			m.versions.Offline = OfflineDetector(true, nil)
+			tool := testTool("tool", map[string]config.InstallSpec{"linux": {Npm: "npm install -g tool"}})
+			tool.Mirror = tt.mirror
+			tool.Version = tt.constraint
+
+			plan := m.PlanInstall(context.Background(), &tool, tt.method, tt.version)
+			if tt.wantErr != "" {
+				if !strings.Contains(plan.Error, tt.wantErr) {
+					t.Errorf("PlanInstall() error = %q, want %q", plan.Error, tt.wantErr)
+				}
+				return
+			}
+			if plan.Error != "" {
+				t.Fatalf("PlanInstall() failed: %s", plan.Error)
+			}
+			if plan.Method != tt.wantMethod || !strings.Contains(plan.Command, filepath.Join(dir, tt.wantFile)) {
+				t.Errorf("PlanInstall() = %s %q, want %s installing %s", plan.Method, plan.Command, tt.wantMethod, tt.wantFile)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+)
+
+// maxOperationLogs is how many operation logs are kept; older ones are deleted
+const maxOperationLogs = 200
+
+// OperationLog records a command run by an install, update, uninstall or repair
+type OperationLog struct {
+	Tool      string            `json:"tool"`
+	Operation string            `json:"operation"`      // what the user asked for, e.g. repair
+	Step      string            `json:"step,omitempty"` // the command's part in it, e.g. uninstall
+	Method    string            `json:"method"`
+	Command   string            `json:"command"`
+	Env       map[string]string `json:"env,omitempty"` // variables set on top of the inherited environment
+	StartedAt time.Time         `json:"started_at"`
+	Duration  time.Duration     `json:"duration"`
+	ExitCode  int               `json:"exit_code"`
+	Error     string            `json:"error,omitempty"`
+	Stdout    string            `json:"stdout"`
+	Stderr    string            `json:"stderr"`
+}
+
+// Failed reports whether the command failed
+func (l *OperationLog) Failed() bool {
+	return l.ExitCode != 0 || l.Error != ""
+}
+
+type operationKey struct{}
+
+// WithOperation labels the commands run with the returned context as part of an
+// operation, e.g. the uninstall and install of a repair
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+// withDefaultOperation labels a context unless an outer operation did already
+func withDefaultOperation(ctx context.Context, operation string) context.Context {
+	if _, ok := ctx.Value(operationKey{}).(string); ok {
+		return ctx
+	}
+	return WithOperation(ctx, operation)
+}
+
+// newOperationLog starts the log of a command run as step of the context's operation
+func newOperationLog(ctx context.Context, tool *config.ToolDefinition, step, method, command string, env []string) *OperationLog {
+	operation, ok := ctx.Value(operationKey{}).(string)
+	if !ok {
+		operation = step
+	}
+	entry := &OperationLog{
+		Tool:      tool.Key,
+		Operation: operation,
+		Method:    method,
+		Command:   redactSecrets(command),
+		Env:       envDelta(env),
+		StartedAt: time.Now(),
+	}
+	if step != operation {
+		entry.Step = step
+	}
+	return entry
+}
+
+// finish records the outcome of the command and writes the log; failures to write
+// only cost the log
+func (l *OperationLog) finish(out *commandOutput, err error) {
+	l.Duration = time.Since(l.StartedAt).Round(time.Millisecond)
+	l.Stdout = out.stdout.String()
+	l.Stderr = out.stderr.String()
+	if err != nil {
+		l.Error = err.Error()
+		l.ExitCode = -1
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+			l.ExitCode = exitErr.ExitCode()
+		}
+	}
+	saveOperationLog(l)
+}
+
+// OperationLogDir returns the directory holding operation logs
+func OperationLogDir() string {
+	paths, err := platform.GetPaths()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(paths.DataDir, "logs")
+}
+
+func saveOperationLog(entry *OperationLog) {
+	dir := OperationLogDir()
+	if dir == "" {
+		return
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return
+	}
+
+	// Names sort by time, so rotation and reading need no parsing
+	name := fmt.Sprintf("%s-%s-%s.json", entry.StartedAt.UTC().Format("20060102T150405.000000000Z"), entry.Tool, entry.Operation)
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		return
+	}
+	rotateOperationLogs(dir)
+}
+
+// rotateOperationLogs deletes the oldest logs beyond maxOperationLogs
+func rotateOperationLogs(dir string) {
+	files := operationLogFiles(dir)
+	for len(files) > maxOperationLogs {
+		os.Remove(filepath.Join(dir, files[0]))
+		files = files[1:]
+	}
+}
+
+// operationLogFiles returns the log file names, oldest first
+func operationLogFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			files = append(files, e.Name())
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// ReadOperationLogs returns up to limit logs, oldest first, optionally only those of one
+// tool or of failed commands. A limit of zero returns all matching logs.
+func ReadOperationLogs(tool string, failedOnly bool, limit int) ([]OperationLog, error) {
+	dir := OperationLogDir()
+	if dir == "" {
+		return nil, fmt.Errorf("could not determine the log directory")
+	}
+
+	files := operationLogFiles(dir)
+	var logs []OperationLog
+	for i := len(files) - 1; i >= 0; i-- {
+		if limit > 0 && len(logs) == limit {
+			break
+		}
+		data, err := os.ReadFile(filepath.Join(dir, files[i]))
+		if err != nil {
+			continue
+		}
+		var entry OperationLog
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		if tool != "" && entry.Tool != tool {
+			continue
+		}
+		if failedOnly && !entry.Failed() {
+			continue
+		}
+		logs = append(logs, entry)
+	}
+
+	// Collected newest first
+	for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+		logs[i], logs[j] = logs[j], logs[i]
+	}
+	return logs, nil
+}
+
+// envDelta returns the variables of a command environment that differ from the
+// inherited one; a nil environment inherits everything
+func envDelta(env []string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	delta := make(map[string]string)
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		if current, ok := os.LookupEnv(key); ok && current == value {
+			continue
+		}
+		if secretName.MatchString(key) {
+			value = "***"
+		}
+		delta[key] = redactSecrets(value)
+	}
+	if len(delta) == 0 {
+		return nil
+	}
+	return delta
+}
+
+var (
+	secretName  = regexp.MustCompile(`(?i)token|secret|password|passwd|auth|key`)
+	urlUserinfo = regexp.MustCompile(`(://)[^/@\s]+@`)
+	secretFlag  = regexp.MustCompile(`(?i)(--?[\w-]*(?:token|password|secret)[\w-]*[= ])\S+`)
+)
+
+// redactSecrets hides credentials in URLs and token flags before they are logged
+func redactSecrets(s string) string {
+	s = urlUserinfo.ReplaceAllString(s, "${1}***@")
+	return secretFlag.ReplaceAllString(s, "${1}***")
+}
@@ -0,0 +1,260 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+)
+
+// Plan describes what an operation on a tool would do, resolved without running anything
+type Plan struct {
+	Tool           string   `json:"tool"`
+	Name           string   `json:"name"`
+	Action         string   `json:"action"` // install, update, uninstall or skip
+	Method         string   `json:"method,omitempty"`
+	Command        string   `json:"command,omitempty"`
+	CurrentVersion string   `json:"current_version,omitempty"`
+	TargetVersion  string   `json:"target_version,omitempty"`
+	NeedsSudo      bool     `json:"needs_sudo"`
+	Purge          []string `json:"purge,omitempty"`  // config paths an uninstall would delete
+	Reason         string   `json:"reason,omitempty"` // why the tool is skipped
+	Error          string   `json:"error,omitempty"`  // why the operation would fail
+}
+
+func newPlan(tool *config.ToolDefinition, action string) *Plan {
+	return &Plan{Tool: tool.Key, Name: tool.Name, Action: action}
+}
+
+// setCommand records the command the operation would run, as it would run it
+func (p *Plan) setCommand(tool *config.ToolDefinition, method, command string) {
+	p.Method = method
+	p.Command = applyRegistry(tool, method, command)
+	p.NeedsSudo = needsSudo(method, p.Command)
+}
+
+// PlanInstall resolves how a tool would be installed, optionally at an exact version. The
+// method is used when the tool defines it on this platform.
+func (m *Manager) PlanInstall(ctx context.Context, tool *config.ToolDefinition, method, version string) *Plan {
+	return m.planInstall(ctx, tool, method, version, true)
+}
+
+func (m *Manager) planInstall(ctx context.Context, tool *config.ToolDefinition, method, version string, skipInstalled bool) *Plan {
+	plan := newPlan(tool, "install")
+	if current, err := m.GetInstalledVersion(ctx, tool); err == nil {
+		plan.CurrentVersion = current
+		if skipInstalled && (version == "" || SameVersion(current, version)) {
+			plan.Action = "skip"
+			plan.Reason = "already installed"
+			return plan
+		}
+	}
+
+	if version != "" {
+		plan.TargetVersion = version
+		method, command, err := m.versionInstallCommand(tool, method, version)
+		if err != nil {
+			plan.Method = method
+			plan.Error = err.Error()
+			return plan
+		}
+		plan.setCommand(tool, method, command)
+		return plan
+	}
+
+	if m.useMirror() {
+		method, command, version, err := m.mirrorInstallCommand(tool, method, "")
+		plan.TargetVersion = version
+		if err != nil {
+			plan.Method = method
+			plan.Error = err.Error()
+			return plan
+		}
+		plan.setCommand(tool, method, command)
+		return plan
+	}
+
+	command := ""
+	if method != "" {
+		command = tool.Install[m.platform.GetOSKey()].ForMethod(method)
+	}
+	if command == "" {
+		method, command = m.GetBestInstallMethod(tool)
+	}
+	if method == "" {
+		plan.Error = fmt.Sprintf("no installation method available for %s on %s", tool.Name, m.platform.String())
+		return plan
+	}
+
+	plan.TargetVersion, _ = m.versions.LatestAllowedVersion(ctx, tool)
+	command, err := m.resolveInstallCommand(ctx, tool, method, command)
+	if err != nil {
+		plan.Method = method
+		plan.Error = err.Error()
+		return plan
+	}
+	plan.setCommand(tool, method, command)
+	return plan
+}
+
+// PlanInstallTools resolves how the given tools would be installed, in the order their
+// dependencies allow
+func (m *Manager) PlanInstallTools(ctx context.Context, tools []config.ToolDefinition, preferredMethod string) []*Plan {
+	plans := make([]*Plan, len(tools))
+	forEachTool(tools, statusConcurrency, func(idx int, tool *config.ToolDefinition) {
+		plans[idx] = m.PlanInstall(ctx, tool, preferredMethod, "")
+	})
+	return inDependencyOrder(tools, plans)
+}
+
+// PlanUpdate resolves how a tool would be updated to its latest allowed version, or to an
+// exact version when one is given
+func (m *Manager) PlanUpdate(ctx context.Context, tool *config.ToolDefinition, version string) *Plan {
+	plan := newPlan(tool, "update")
+	current, err := m.GetInstalledVersion(ctx, tool)
+	if err != nil {
+		plan.Action = "skip"
+		plan.Reason = "not installed"
+		return plan
+	}
+	plan.CurrentVersion = current
+
+	if version != "" {
+		plan.TargetVersion = version
+		if SameVersion(current, version) {
+			plan.Action = "skip"
+			plan.Reason = "already at the requested version"
+			return plan
+		}
+		installed, _ := m.InstalledMethod(ctx, tool)
+		method, command, err := m.versionInstallCommand(tool, installed, version)
+		if err != nil {
+			plan.Method = method
+			plan.Error = err.Error()
+			return plan
+		}
+		plan.setCommand(tool, method, command)
+		return plan
+	}
+
+	latest, err := m.updateTarget(ctx, tool)
+	if err != nil && tool.Version != "" {
+		plan.Error = fmt.Sprintf("could not resolve a version matching %s: %v", tool.Version, err)
+		return plan
+	}
+	if err == nil {
+		plan.TargetVersion = latest
+		if hasUpdate, err := m.CompareVersions(current, latest); err == nil && !hasUpdate {
+			plan.Action = "skip"
+			plan.Reason = "up to date"
+			return plan
+		}
+	}
+
+	method, command, err := m.updateCommand(ctx, tool, latest)
+	if err != nil {
+		plan.Method = method
+		plan.Error = err.Error()
+		return plan
+	}
+	plan.setCommand(tool, method, command)
+	return plan
+}
+
+// PlanUpdateAll resolves how all installed tools would be updated
+func (m *Manager) PlanUpdateAll(ctx context.Context) []*Plan {
+	tools := config.GetAllTools()
+	plans := make([]*Plan, len(tools))
+	forEachTool(tools, statusConcurrency, func(idx int, tool *config.ToolDefinition) {
+		plans[idx] = m.PlanUpdate(ctx, tool, "")
+	})
+	return inDependencyOrder(tools, plans)
+}
+
+// PlanUninstall resolves how a tool would be uninstalled
+func (m *Manager) PlanUninstall(ctx context.Context, tool *config.ToolDefinition) *Plan {
+	plan := newPlan(tool, "uninstall")
+	plan.CurrentVersion, _ = m.GetInstalledVersion(ctx, tool)
+
+	method, command, err := m.uninstallCommand(ctx, tool)
+	if err != nil {
+		plan.Error = err.Error()
+		return plan
+	}
+	plan.Method = method
+	plan.Command = command
+	plan.NeedsSudo = needsSudo(method, command)
+	return plan
+}
+
+// PlanRepair resolves the uninstall and reinstall of a repair, which reinstalls the tool
+// with the method it is installed with
+func (m *Manager) PlanRepair(ctx context.Context, tool *config.ToolDefinition) []*Plan {
+	method, _ := m.InstalledMethod(ctx, tool)
+	return []*Plan{
+		m.PlanUninstall(ctx, tool),
+		m.planInstall(ctx, tool, method, "", false),
+	}
+}
+
+// PlanDependencies resolves the installs of the missing dependencies of a tool
+func (m *Manager) PlanDependencies(ctx context.Context, tool *config.ToolDefinition) []*Plan {
+	var plans []*Plan
+	for _, dep := range m.MissingDependencies(ctx, tool) {
+		dep := dep
+		plans = append(plans, m.planInstall(ctx, &dep, "", "", false))
+	}
+	return plans
+}
+
+// inDependencyOrder orders the plans, or other items, of tools so that every tool comes
+// after the tools it depends on; tools in a dependency cycle come last
+func inDependencyOrder[T any](tools []config.ToolDefinition, plans []T) []T {
+	index := make(map[string]int, len(tools))
+	for i, t := range tools {
+		index[t.Key] = i
+	}
+
+	placed := make([]bool, len(tools))
+	ordered := make([]T, 0, len(plans))
+	for progress := true; progress; {
+		progress = false
+		for i, t := range tools {
+			if placed[i] {
+				continue
+			}
+			ready := true
+			for _, dep := range t.DependsOn {
+				if j, ok := index[dep]; ok && !placed[j] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				placed[i] = true
+				ordered = append(ordered, plans[i])
+				progress = true
+			}
+		}
+	}
+	for i := range tools {
+		if !placed[i] {
+			ordered = append(ordered, plans[i])
+		}
+	}
+	return ordered
+}
+
+// needsSudo reports whether a command needs administrator rights: it calls sudo, or it
+// uses a system package manager without running as root
+func needsSudo(method, command string) bool {
+	for _, field := range strings.Fields(command) {
+		if field == "sudo" {
+			return true
+		}
+	}
+	return (method == "apt" || method == "pacman") && !platform.IsWindows() && os.Geteuid() != 0
+}
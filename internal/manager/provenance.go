@@ -0,0 +1,250 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+)
+
+// probeTimeout bounds each package manager query of the provenance detection
+const probeTimeout = 5 * time.Second
+
+// Provenance records how an installed tool got onto the machine
+type Provenance struct {
+	Method string    `json:"method"`         // install method, empty when unknown
+	Path   string    `json:"path,omitempty"` // resolved path of the tool's binary
+	Source string    `json:"source"`         // "install" when agenthelper installed the tool, else "detected"
+	Time   time.Time `json:"time"`
+}
+
+// installState is the content of the state file
+type installState struct {
+	Tools map[string]*Provenance `json:"tools"`
+}
+
+// stateMu serializes updates of the state file by concurrent installs
+var stateMu sync.Mutex
+
+// StateFile returns the path of the file recording how each tool is installed
+func StateFile() string {
+	paths, err := platform.GetPaths()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(paths.DataDir, "state.json")
+}
+
+func loadState() *installState {
+	state := &installState{Tools: make(map[string]*Provenance)}
+	path := StateFile()
+	if path == "" {
+		return state
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, state); err != nil || state.Tools == nil {
+		return &installState{Tools: make(map[string]*Provenance)}
+	}
+	return state
+}
+
+// saveProvenance records the provenance of a tool, or forgets it when p is nil; failures
+// only cost the record
+func saveProvenance(key string, p *Provenance) {
+	path := StateFile()
+	if path == "" {
+		return
+	}
+
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state := loadState()
+	if p == nil {
+		delete(state.Tools, key)
+	} else {
+		state.Tools[key] = p
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	os.Rename(tmp, path)
+}
+
+// recordProvenance remembers the method agenthelper installed or updated a tool with
+func (m *Manager) recordProvenance(tool *config.ToolDefinition, method string) {
+	path, _ := m.toolPath(tool)
+	saveProvenance(tool.Key, &Provenance{Method: method, Path: path, Source: "install", Time: time.Now()})
+}
+
+// toolPath returns the resolved path of a tool's binary on PATH. Tools run as a
+// subcommand of another binary, such as "gh copilot", have no path of their own.
+func (m *Manager) toolPath(tool *config.ToolDefinition) (string, error) {
+	fields := strings.Fields(tool.Command)
+	if len(fields) != 1 {
+		return "", nil
+	}
+	path, err := m.sys.Path.LookPath(fields[0])
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved, nil
+	}
+	return path, nil
+}
+
+// Provenance returns how an installed tool got onto the machine. The recorded provenance
+// is used while the tool's binary stays where it was recorded; otherwise the binary is
+// inspected and the result recorded. It returns nil when the tool is not on PATH.
+func (m *Manager) Provenance(ctx context.Context, tool *config.ToolDefinition) *Provenance {
+	path, err := m.toolPath(tool)
+	if err != nil {
+		return nil
+	}
+	recorded, ok := loadState().Tools[tool.Key]
+	if ok && recorded.Path == path {
+		return recorded
+	}
+	if path == "" {
+		return nil // Nothing to inspect, and nothing recorded
+	}
+
+	p := &Provenance{Method: m.detectMethod(ctx, tool, path), Path: path, Source: "detected", Time: time.Now()}
+	// A copy shadowing the one agenthelper installed does not replace its record
+	if ok && recorded.Source == "install" && fileExists(recorded.Path) {
+		return p
+	}
+	if p.Method != "" {
+		saveProvenance(tool.Key, p)
+	}
+	return p
+}
+
+// ManagedPath returns the path of the copy of a tool that agenthelper installed, or ""
+// when it did not install the tool or cannot tell its path
+func ManagedPath(tool *config.ToolDefinition) string {
+	if p, ok := loadState().Tools[tool.Key]; ok && p.Source == "install" {
+		return p.Path
+	}
+	return ""
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return path != "" && err == nil
+}
+
+// InstalledMethod returns the method a tool is installed with and its install command for
+// this platform. The best available method is returned when the provenance is unknown or
+// the tool defines no install command for it here.
+func (m *Manager) InstalledMethod(ctx context.Context, tool *config.ToolDefinition) (string, string) {
+	if p := m.Provenance(ctx, tool); p != nil && p.Method != "" {
+		if command := tool.Install[m.platform.GetOSKey()].ForMethod(p.Method); command != "" {
+			return p.Method, command
+		}
+	}
+	return m.GetBestInstallMethod(tool)
+}
+
+// detectMethod tells from where a binary lives, and by asking the package managers, which
+// install method put it there. It returns "" when no method claims the binary.
+func (m *Manager) detectMethod(ctx context.Context, tool *config.ToolDefinition, path string) string {
+	slashed := filepath.ToSlash(path)
+	lower := strings.ToLower(slashed)
+
+	switch {
+	case strings.HasSuffix(lower, ".appimage"):
+		return "script"
+	case strings.Contains(slashed, "/Cellar/") || strings.Contains(slashed, "/Caskroom/"):
+		return "brew"
+	case strings.Contains(slashed, "/node_modules/"):
+		return "npm"
+	case strings.Contains(slashed, "/pipx/venvs/") || strings.Contains(lower, "/site-packages/"):
+		return "pip"
+	case strings.Contains(lower, "/microsoft/winget/"):
+		return "winget"
+	}
+
+	if isPythonEntryPoint(path) {
+		return "pip"
+	}
+
+	if m.platform.OS == platform.Windows {
+		// npm shims live in the global prefix instead of linking into node_modules
+		if prefix, err := m.probe(ctx, "npm", "prefix", "-g"); err == nil && strings.EqualFold(filepath.Dir(path), filepath.Clean(prefix)) {
+			return "npm"
+		}
+		if id := wingetID(tool.Install[m.platform.GetOSKey()].WinGet); id != "" {
+			if out, err := m.probe(ctx, "winget", "list", "--id", id, "-e"); err == nil && strings.Contains(strings.ToLower(out), strings.ToLower(id)) {
+				return "winget"
+			}
+		}
+		return ""
+	}
+
+	if _, err := m.probe(ctx, "dpkg", "-S", path); err == nil {
+		return "apt"
+	}
+	if _, err := m.probe(ctx, "pacman", "-Qo", path); err == nil {
+		return "pacman"
+	}
+	return ""
+}
+
+// isPythonEntryPoint reports whether a file is a script started by a Python interpreter,
+// as pip installs console entry points
+func isPythonEntryPoint(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 256)
+	n, _ := f.Read(head)
+	line, _, _ := strings.Cut(string(head[:n]), "\n")
+	return strings.HasPrefix(line, "#!") && strings.Contains(line, "python")
+}
+
+// wingetID returns the package id of a winget install command
+func wingetID(command string) string {
+	fields := strings.Fields(command)
+	if id := flagValue(fields, "--id"); id != "" {
+		return id
+	}
+	return lastArg(fields)
+}
+
+// probe runs a query command, returning its trimmed output; it fails when the command is
+// missing, exits non-zero or takes longer than probeTimeout
+func (m *Manager) probe(ctx context.Context, name string, args ...string) (string, error) {
+	if !m.sys.HasCommand(name) {
+		return "", fmt.Errorf("%s not found", name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	line := name
+	for _, arg := range args {
+		line += " " + m.sys.QuoteArg(arg)
+	}
+	return m.sys.Output(ctx, line)
+}
@@ -0,0 +1,197 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jschneider/agenthelper/internal/config"
+)
+
+// DefaultJobs is the default number of tool operations run at the same time
+const DefaultJobs = 4
+
+// statusConcurrency limits concurrent version checks of status and lock
+const statusConcurrency = 8
+
+// exclusiveMethods are package managers that hold a system-wide lock, so only one
+// operation may use each of them at a time
+var exclusiveMethods = map[string]bool{
+	"apt":    true,
+	"brew":   true,
+	"winget": true,
+	"pacman": true,
+}
+
+// scheduledOp runs an operation on a tool; an error keeps the tools depending on it from running
+type scheduledOp func(ctx context.Context, tool *config.ToolDefinition) error
+
+// runScheduled runs op for every tool, at most Options.Jobs at a time. A tool starts after the
+// tools of the run it depends on have succeeded, and operations using the same exclusive
+// package manager never overlap. methodFor returns the method an operation will use.
+// The returned map holds the scheduling error of every tool that was not run.
+func (m *Manager) runScheduled(ctx context.Context, tools []config.ToolDefinition, methodFor func(*config.ToolDefinition) string, op scheduledOp) map[string]error {
+	const (
+		pending = iota
+		running
+		succeeded
+		failed
+	)
+
+	inRun := make(map[string]int, len(tools))
+	for i, tool := range tools {
+		inRun[tool.Key] = i
+	}
+
+	state := make([]int, len(tools))
+	skipped := make(map[string]error)
+	locks := make(map[string]bool)
+
+	type completion struct {
+		idx  int
+		lock string
+		err  error
+	}
+	done := make(chan completion)
+	active, finished := 0, 0
+
+	for finished < len(tools) {
+		progress := true
+		for progress {
+			progress = false
+			for i := range tools {
+				if state[i] != pending {
+					continue
+				}
+				tool := &tools[i]
+
+				if ctx.Err() != nil {
+					state[i] = failed
+					skipped[tool.Key] = ctx.Err()
+					finished++
+					progress = true
+					continue
+				}
+
+				ready := true
+				for _, dep := range tool.DependsOn {
+					j, ok := inRun[dep]
+					if !ok {
+						continue
+					}
+					if state[j] == failed {
+						state[i] = failed
+						skipped[tool.Key] = fmt.Errorf("skipped because %s failed", dep)
+						finished++
+						progress = true
+						ready = false
+						break
+					}
+					if state[j] != succeeded {
+						ready = false
+						break
+					}
+				}
+				if state[i] != pending || !ready || active >= m.opts.Jobs {
+					continue
+				}
+
+				lock := methodFor(tool)
+				if !exclusiveMethods[lock] {
+					lock = ""
+				}
+				if lock != "" && locks[lock] {
+					continue
+				}
+
+				state[i] = running
+				active++
+				if lock != "" {
+					locks[lock] = true
+				}
+				go func(idx int, lock string) {
+					done <- completion{idx: idx, lock: lock, err: op(ctx, &tools[idx])}
+				}(i, lock)
+			}
+		}
+
+		if active == 0 {
+			// Nothing runs and nothing can start: the remaining tools depend on each other
+			for i := range tools {
+				if state[i] == pending {
+					state[i] = failed
+					skipped[tools[i].Key] = fmt.Errorf("dependency cycle involving %s", tools[i].Key)
+					finished++
+				}
+			}
+			break
+		}
+
+		c := <-done
+		active--
+		finished++
+		if c.lock != "" {
+			delete(locks, c.lock)
+		}
+		if c.err != nil {
+			state[c.idx] = failed
+		} else {
+			state[c.idx] = succeeded
+		}
+	}
+
+	return skipped
+}
+
+// forEachTool runs fn for every tool with bounded concurrency and no ordering
+func forEachTool(tools []config.ToolDefinition, limit int, fn func(idx int, tool *config.ToolDefinition)) {
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := range tools {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(idx, &tools[idx])
+		}(i)
+	}
+	wg.Wait()
+}
+
+// MissingDependencies returns the tools a tool depends on, directly or indirectly, that
+// are not installed, in the order they must be installed
+func (m *Manager) MissingDependencies(ctx context.Context, tool *config.ToolDefinition) []config.ToolDefinition {
+	var missing []config.ToolDefinition
+	visited := map[string]bool{tool.Key: true}
+
+	var visit func(t *config.ToolDefinition)
+	visit = func(t *config.ToolDefinition) {
+		for _, key := range t.DependsOn {
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+			dep, ok := config.GetTool(key)
+			if !ok {
+				continue
+			}
+			visit(dep)
+			if _, err := m.GetInstalledVersion(ctx, dep); err != nil {
+				missing = append(missing, *dep)
+			}
+		}
+	}
+	visit(tool)
+	return missing
+}
+
+// DescribeTools joins the names of tools for messages
+func DescribeTools(tools []config.ToolDefinition) string {
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,2 @@
+#!/bin/sh
+echo "installing tool 2.3.0"
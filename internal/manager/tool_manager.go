@@ -2,10 +2,12 @@ package manager
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"os/exec"
+	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/jschneider/agenthelper/internal/config"
@@ -18,22 +20,73 @@ type ToolStatus struct {
 	IsInstalled    bool
 	InstalledVer   string
 	LatestVer      string
+	TargetVer      string // highest version allowed by the tool's version constraint
+	Method         string // method the installed tool was installed with, if known
 	HasUpdate      bool
 	InstallMethods []string
 	Error          error
 }
 
+// Options are the settings of a manager and its version checker, from flags and the
+// config file
+type Options struct {
+	// VersionSources replaces the endpoints of version sources for all tools, see
+	// VersionSourceURLs
+	VersionSources map[string]string
+	// CacheTTL is how long a cached version lookup is used without revalidation
+	CacheTTL time.Duration
+	// Refresh bypasses cached version lookups
+	Refresh bool
+	// Offline reports whether the network is unavailable, see OfflineDetector; nil means online
+	Offline func() bool
+	// Mirror is the directory of pre-downloaded packages offline installs come from
+	Mirror string
+	// AssumeYes runs verified downloads without confirmation
+	AssumeYes bool
+	// Interactive allows asking for confirmation on the terminal
+	Interactive bool
+	// VerifiedOnly refuses unverified script installs
+	VerifiedOnly bool
+	// StreamOutput shows the output of install, update and uninstall commands on the
+	// terminal while they run. The output is captured for the result either way.
+	StreamOutput bool
+	// Jobs is how many install and update operations may run at the same time
+	Jobs int
+}
+
+// DefaultOptions returns the settings used without flags or a config file
+func DefaultOptions() Options {
+	return Options{CacheTTL: DefaultCacheTTL, Jobs: DefaultJobs}
+}
+
 // Manager handles tool operations
 type Manager struct {
 	platform *platform.Platform
+	sys      *platform.System
 	managers []platform.PackageManager
+	versions *VersionChecker
+	opts     Options
+	confirm  sync.Mutex // one download confirmation on the terminal at a time
+	terminal sync.Mutex // held by a streamed command that may prompt, see runToolCommand
 }
 
 // NewManager creates a new tool manager
-func NewManager() *Manager {
+func NewManager(opts Options) *Manager {
+	return NewManagerFor(platform.LocalSystem(), opts)
+}
+
+// NewManagerFor creates a tool manager that runs its commands and finds executables
+// through the given system, e.g. a simulated one in tests
+func NewManagerFor(sys *platform.System, opts Options) *Manager {
+	if opts.Jobs < 1 {
+		opts.Jobs = 1
+	}
 	return &Manager{
-		platform: platform.Current(),
-		managers: platform.DetectPackageManagers(),
+		platform: sys.Platform(),
+		sys:      sys,
+		managers: sys.DetectPackageManagers(),
+		versions: NewVersionChecker(&http.Client{}, sys.Runner, opts),
+		opts:     opts,
 	}
 }
 
@@ -43,30 +96,44 @@ func (m *Manager) GetPlatform() *platform.Platform {
 }
 
 // GetToolStatus returns the status of a single tool
-func (m *Manager) GetToolStatus(tool *config.ToolDefinition) *ToolStatus {
+func (m *Manager) GetToolStatus(ctx context.Context, tool *config.ToolDefinition) *ToolStatus {
 	status := &ToolStatus{
 		Tool: tool,
 	}
 
 	// Check if installed
-	installedVersion, err := m.GetInstalledVersion(tool)
+	installedVersion, err := m.GetInstalledVersion(ctx, tool)
 	if err == nil && installedVersion != "" {
 		status.IsInstalled = true
 		status.InstalledVer = installedVersion
+		if p := m.Provenance(ctx, tool); p != nil {
+			status.Method = p.Method
+		}
 	}
 
 	// Get latest version
-	latestVersion, err := GetLatestVersion(tool)
+	latestVersion, err := m.versions.LatestVersion(ctx, tool)
 	if err == nil && latestVersion != "" {
 		status.LatestVer = latestVersion
+		status.TargetVer = latestVersion
+	}
 
-		// Compare versions
-		if status.IsInstalled && status.InstalledVer != "" {
-			hasUpdate, _ := m.CompareVersions(status.InstalledVer, latestVersion)
-			status.HasUpdate = hasUpdate
+	// Only versions within the tool's constraint count as updates
+	if tool.Version != "" {
+		status.TargetVer = ""
+		if targetVersion, err := m.versions.LatestAllowedVersion(ctx, tool); err == nil {
+			status.TargetVer = targetVersion
+		} else {
+			status.Error = err
 		}
 	}
 
+	// Compare versions
+	if status.IsInstalled && status.InstalledVer != "" && status.TargetVer != "" {
+		hasUpdate, _ := m.CompareVersions(status.InstalledVer, status.TargetVer)
+		status.HasUpdate = hasUpdate
+	}
+
 	// Get available install methods
 	status.InstallMethods = m.GetAvailableInstallMethods(tool)
 
@@ -74,38 +141,32 @@ func (m *Manager) GetToolStatus(tool *config.ToolDefinition) *ToolStatus {
 }
 
 // GetAllToolStatus returns status for all tools
-func (m *Manager) GetAllToolStatus() []*ToolStatus {
+func (m *Manager) GetAllToolStatus(ctx context.Context) []*ToolStatus {
 	tools := config.GetAllTools()
 	statuses := make([]*ToolStatus, len(tools))
 
-	var wg sync.WaitGroup
-	for i, tool := range tools {
-		wg.Add(1)
-		go func(idx int, t config.ToolDefinition) {
-			defer wg.Done()
-			statuses[idx] = m.GetToolStatus(&t)
-		}(i, tool)
-	}
-	wg.Wait()
+	forEachTool(tools, statusConcurrency, func(idx int, t *config.ToolDefinition) {
+		statuses[idx] = m.GetToolStatus(ctx, t)
+	})
 
 	return statuses
 }
 
-// GetInstalledVersion returns the installed version of a tool
-func (m *Manager) GetInstalledVersion(tool *config.ToolDefinition) (string, error) {
+// GetInstalledVersion returns the installed version of a tool. The version command is
+// killed after the tool's version timeout, e.g. when it waits for first-run input.
+func (m *Manager) GetInstalledVersion(ctx context.Context, tool *config.ToolDefinition) (string, error) {
 	if tool.VersionCmd == "" {
 		return "", fmt.Errorf("no version command defined")
 	}
 
-	cmd := platform.NewShellCommand(tool.VersionCmd)
+	timeout := tool.VersionTimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	err := m.sys.Runner.Run(ctx, &platform.Command{Line: tool.VersionCmd, Stdout: &stdout, Stderr: &stderr})
 	if err != nil {
-		return "", fmt.Errorf("command failed: %w", err)
+		return "", fmt.Errorf("command failed: %w", contextError(ctx, timeout, err))
 	}
 
 	output := stdout.String()
@@ -152,43 +213,56 @@ func (m *Manager) GetAvailableInstallMethods(tool *config.ToolDefinition) []stri
 	}
 
 	// Check each method
-	if installSpec.WinGet != "" && platform.IsWindows() {
-		if pm := platform.NewWinGet(); pm.IsAvailable() {
+	if installSpec.WinGet != "" && m.platform.OS == platform.Windows {
+		if m.hasPackageManager("winget") {
 			methods = append(methods, "winget")
 		}
 	}
 	if installSpec.Brew != "" {
-		if pm := platform.NewHomebrew(); pm.IsAvailable() {
+		if m.hasPackageManager("brew") {
 			methods = append(methods, "brew")
 		}
 	}
 	if installSpec.Apt != "" {
-		if pm := platform.NewApt(); pm.IsAvailable() {
+		if m.hasPackageManager("apt") {
 			methods = append(methods, "apt")
 		}
 	}
 	if installSpec.Pacman != "" {
-		if pm := platform.NewPacman(); pm.IsAvailable() {
+		if m.hasPackageManager("pacman") {
 			methods = append(methods, "pacman")
 		}
 	}
 	if installSpec.Npm != "" {
-		if pm := platform.NewNpm(); pm.IsAvailable() {
+		if m.hasPackageManager("npm") {
 			methods = append(methods, "npm")
 		}
 	}
 	if installSpec.Pip != "" {
-		if pm := platform.NewPip(); pm.IsAvailable() {
+		if m.hasPackageManager("pip") {
 			methods = append(methods, "pip")
 		}
 	}
-	if installSpec.Script != "" {
+	if installSpec.Download.URL != "" {
+		methods = append(methods, "download")
+	}
+	if installSpec.Script != "" && m.scriptAllowed() {
 		methods = append(methods, "script")
 	}
 
 	return methods
 }
 
+// IsMethodAvailable reports whether a tool can be installed with the given method on this platform
+func (m *Manager) IsMethodAvailable(tool *config.ToolDefinition, method string) bool {
+	for _, available := range m.GetAvailableInstallMethods(tool) {
+		if available == method {
+			return true
+		}
+	}
+	return false
+}
+
 // GetBestInstallMethod returns the preferred install method for a tool
 func (m *Manager) GetBestInstallMethod(tool *config.ToolDefinition) (string, string) {
 	osKey := m.platform.GetOSKey()
@@ -198,35 +272,35 @@ func (m *Manager) GetBestInstallMethod(tool *config.ToolDefinition) (string, str
 	}
 
 	// Priority order varies by platform
-	if platform.IsWindows() {
+	if m.platform.OS == platform.Windows {
 		if installSpec.WinGet != "" {
-			if pm := platform.NewWinGet(); pm.IsAvailable() {
+			if m.hasPackageManager("winget") {
 				return "winget", installSpec.WinGet
 			}
 		}
 	}
 
-	if platform.IsDarwin() {
+	if m.platform.OS == platform.Darwin {
 		if installSpec.Brew != "" {
-			if pm := platform.NewHomebrew(); pm.IsAvailable() {
+			if m.hasPackageManager("brew") {
 				return "brew", installSpec.Brew
 			}
 		}
 	}
 
-	if platform.IsLinux() {
+	if m.platform.OS == platform.Linux {
 		if installSpec.Apt != "" {
-			if pm := platform.NewApt(); pm.IsAvailable() {
+			if m.hasPackageManager("apt") {
 				return "apt", installSpec.Apt
 			}
 		}
 		if installSpec.Brew != "" {
-			if pm := platform.NewHomebrew(); pm.IsAvailable() {
+			if m.hasPackageManager("brew") {
 				return "brew", installSpec.Brew
 			}
 		}
 		if installSpec.Pacman != "" {
-			if pm := platform.NewPacman(); pm.IsAvailable() {
+			if m.hasPackageManager("pacman") {
 				return "pacman", installSpec.Pacman
 			}
 		}
@@ -234,26 +308,42 @@ func (m *Manager) GetBestInstallMethod(tool *config.ToolDefinition) (string, str
 
 	// Cross-platform fallbacks
 	if installSpec.Npm != "" {
-		if pm := platform.NewNpm(); pm.IsAvailable() {
+		if m.hasPackageManager("npm") {
 			return "npm", installSpec.Npm
 		}
 	}
 
 	if installSpec.Pip != "" {
-		if pm := platform.NewPip(); pm.IsAvailable() {
+		if m.hasPackageManager("pip") {
 			return "pip", installSpec.Pip
 		}
 	}
 
-	if installSpec.Script != "" {
+	if command := installSpec.ForMethod("download"); command != "" {
+		return "download", command
+	}
+
+	if installSpec.Script != "" && m.scriptAllowed() {
 		return "script", installSpec.Script
 	}
 
 	return "", ""
 }
 
-// CommandExists checks if a command is available in PATH
-func CommandExists(name string) bool {
-	_, err := exec.LookPath(name)
-	return err == nil
+// hasPackageManager reports whether the named package manager is available
+func (m *Manager) hasPackageManager(name string) bool {
+	pm := m.sys.PackageManager(name)
+	return pm != nil && pm.IsAvailable()
+}
+
+// contextError explains why a command stopped if its context ended first
+func contextError(ctx context.Context, timeout time.Duration, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("timed out after %s: %w", timeout, context.DeadlineExceeded)
+	case context.Canceled:
+		return context.Canceled
+	default:
+		return err
+	}
 }
@@ -0,0 +1,170 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+	"github.com/jschneider/agenthelper/internal/platform/platformtest"
+)
+
+// testVersions is a version source for tests; its package lists the published versions,
+// oldest first, e.g. "1.0.0,1.1.0"
+type testVersions struct{}
+
+func (testVersions) Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error) {
+	versions, err := testVersions{}.Versions(ctx, c, source)
+	if err != nil {
+		return "", err
+	}
+	return versions[len(versions)-1], nil
+}
+
+func (testVersions) Versions(ctx context.Context, c *VersionChecker, source config.VersionSource) ([]string, error) {
+	if source.Package == "" {
+		return nil, fmt.Errorf("no versions published")
+	}
+	return strings.Split(source.Package, ","), nil
+}
+
+func init() {
+	RegisterVersionProvider("test", testVersions{})
+}
+
+// newTestManager returns a manager for a simulated system with the named executables on
+// PATH. History, logs and state go to a temporary home directory.
+func newTestManager(t *testing.T, os platform.OS, executables ...string) (*Manager, *platformtest.Runner) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	sys, runner := platformtest.NewSystem(os, executables...)
+	return NewManagerFor(sys, DefaultOptions()), runner
+}
+
+// setTools replaces the loaded tool definitions for the duration of a test
+func setTools(t *testing.T, tools ...config.ToolDefinition) {
+	t.Helper()
+	appConfig, toolsMap := config.AppConfig, config.ToolsMap
+	t.Cleanup(func() {
+		config.AppConfig, config.ToolsMap = appConfig, toolsMap
+	})
+
+	config.AppConfig = &config.Config{Tools: tools}
+	config.ToolsMap = make(map[string]*config.ToolDefinition)
+	for i := range config.AppConfig.Tools {
+		config.ToolsMap[tools[i].Key] = &config.AppConfig.Tools[i]
+	}
+}
+
+// changes returns the commands run that change the system, leaving out version commands
+// and the queries of the provenance detection
+func changes(runner *platformtest.Runner) []string {
+	var ran []string
+	for _, call := range runner.Calls() {
+		for _, verb := range []string{" install", " upgrade", " uninstall", " remove"} {
+			if strings.Contains(call, verb) {
+				ran = append(ran, call)
+				break
+			}
+		}
+	}
+	return ran
+}
+
+// testTool returns a tool whose version command is "<key> --version"
+func testTool(key string, install map[string]config.InstallSpec) config.ToolDefinition {
+	return config.ToolDefinition{
+		Key:        key,
+		Name:       key,
+		Command:    key,
+		VersionCmd: key + " --version",
+		Install:    install,
+	}
+}
+
+func TestGetBestInstallMethod(t *testing.T) {
+	everywhere := func(spec config.InstallSpec) map[string]config.InstallSpec {
+		return map[string]config.InstallSpec{"windows": spec, "darwin": spec, "linux": spec}
+	}
+	all := config.InstallSpec{
+		WinGet: "winget install --id Tool.Tool -e",
+		Brew:   "brew install tool",
+		Apt:    "apt install tool",
+		Pacman: "pacman -S tool",
+		Npm:    "npm install -g tool",
+		Pip:    "pip install tool",
+		Script: "curl -fsSL https://example.com/install.sh | sh",
+	}
+
+	tests := []struct {
+		name        string
+		os          platform.OS
+		executables []string
+		install     map[string]config.InstallSpec
+		wantMethod  string
+		wantCommand string
+	}{
+		{"windows prefers winget", platform.Windows, []string{"winget", "npm", "pip"}, everywhere(all), "winget", all.WinGet},
+		{"windows without winget falls back to npm", platform.Windows, []string{"npm", "pip"}, everywhere(all), "npm", all.Npm},
+		{"windows ignores brew", platform.Windows, []string{"brew"}, everywhere(all), "script", all.Script},
+		{"darwin prefers brew", platform.Darwin, []string{"brew", "npm"}, everywhere(all), "brew", all.Brew},
+		{"darwin ignores apt", platform.Darwin, []string{"apt", "pip"}, everywhere(all), "pip", all.Pip},
+		{"linux prefers apt", platform.Linux, []string{"apt", "brew", "pacman", "npm"}, everywhere(all), "apt", all.Apt},
+		{"linux prefers brew over pacman", platform.Linux, []string{"brew", "pacman"}, everywhere(all), "brew", all.Brew},
+		{"linux uses pacman", platform.Linux, []string{"pacman", "npm"}, everywhere(all), "pacman", all.Pacman},
+		{"npm before pip", platform.Linux, []string{"pip", "npm"}, everywhere(all), "npm", all.Npm},
+		{"pip3 counts as pip", platform.Linux, []string{"pip3"}, everywhere(config.InstallSpec{Pip: all.Pip}), "pip", all.Pip},
+		{"script needs no package manager", platform.Darwin, nil, everywhere(all), "script", all.Script},
+		{"method without a command is skipped", platform.Linux, []string{"apt", "npm"}, everywhere(config.InstallSpec{Npm: all.Npm}), "npm", all.Npm},
+		{"no spec for this OS", platform.Linux, []string{"npm"}, map[string]config.InstallSpec{"windows": all}, "", ""},
+		{"nothing available", platform.Windows, nil, everywhere(config.InstallSpec{Npm: all.Npm}), "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager(t, tt.os, tt.executables...)
+			tool := testTool("tool", tt.install)
+
+			method, command := m.GetBestInstallMethod(&tool)
+			if method != tt.wantMethod || command != tt.wantCommand {
+				t.Errorf("GetBestInstallMethod() = %q, %q; want %q, %q", method, command, tt.wantMethod, tt.wantCommand)
+			}
+		})
+	}
+}
+
+func TestGetInstalledVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		response platformtest.Response
+		pattern  string
+		want     string
+		wantErr  bool
+	}{
+		{"plain version", platformtest.Response{Stdout: "1.2.3\n"}, "", "1.2.3", false},
+		{"version in text", platformtest.Response{Stdout: "tool version v2.0.1 (build 7)\n"}, "", "2.0.1", false},
+		{"version on stderr", platformtest.Response{Stderr: "tool 3.4.5\n"}, "", "3.4.5", false},
+		{"custom pattern", platformtest.Response{Stdout: "tool 1.0.0, api 9.9.9\n"}, `api (\d+\.\d+\.\d+)`, "9.9.9", false},
+		{"command fails", platformtest.Response{ExitCode: 127}, "", "", true},
+		{"no version in output", platformtest.Response{Stdout: "hello\n"}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, runner := newTestManager(t, platform.Linux)
+			runner.On("tool --version", tt.response)
+			tool := testTool("tool", nil)
+			tool.VersionPattern = tt.pattern
+
+			got, err := m.GetInstalledVersion(context.Background(), &tool)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("GetInstalledVersion() = %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jschneider/agenthelper/internal/config"
+)
+
+// DeriveUninstallCommand derives the command that removes what an install command
+// installed. Only the first segment of a chained command (cmd1 && cmd2) is used, since
+// that is the one invoking the package manager.
+func DeriveUninstallCommand(method, installCommand string) (string, error) {
+	first := installCommand
+	if idx := strings.Index(first, "&&"); idx >= 0 {
+		first = first[:idx]
+	}
+	fields := strings.Fields(first)
+
+	sudo := ""
+	if len(fields) > 0 && fields[0] == "sudo" {
+		sudo = "sudo "
+		fields = fields[1:]
+	}
+	if len(fields) < 2 {
+		return "", fmt.Errorf("cannot derive an uninstall command from %q", installCommand)
+	}
+	tool := fields[0]
+
+	switch method {
+	case "npm":
+		pkg := lastArg(fields)
+		// Keep the scope '@' of scoped packages, drop any version suffix
+		if idx := strings.LastIndex(pkg, "@"); idx > 0 {
+			pkg = pkg[:idx]
+		}
+		if pkg == "" {
+			break
+		}
+		return sudo + tool + " uninstall -g " + pkg, nil
+	case "pip":
+		pkg := lastArg(fields)
+		if idx := strings.IndexAny(pkg, "=<>~!["); idx > 0 {
+			pkg = pkg[:idx]
+		}
+		if pkg == "" {
+			break
+		}
+		if tool == "pipx" {
+			return sudo + "pipx uninstall " + pkg, nil
+		}
+		return sudo + tool + " uninstall -y " + pkg, nil
+	case "winget":
+		if id := flagValue(fields, "--id"); id != "" {
+			return "winget uninstall --id " + id + " -e", nil
+		}
+	case "brew":
+		pkg := lastArg(fields)
+		if idx := strings.Index(pkg, "@"); idx > 0 {
+			pkg = pkg[:idx]
+		}
+		if pkg == "" {
+			break
+		}
+		if flagPresent(fields, "--cask") {
+			return "brew uninstall --cask " + pkg, nil
+		}
+		return "brew uninstall " + pkg, nil
+	case "apt":
+		if pkg := lastArg(fields); pkg != "" {
+			if idx := strings.Index(pkg, "="); idx > 0 {
+				pkg = pkg[:idx]
+			}
+			return sudo + tool + " remove -y " + pkg, nil
+		}
+	case "pacman":
+		if pkg := lastArg(fields); pkg != "" {
+			return sudo + "pacman -R --noconfirm " + pkg, nil
+		}
+	default:
+		return "", fmt.Errorf("%s installs cannot be reversed automatically; add an uninstall spec", method)
+	}
+	return "", fmt.Errorf("cannot derive an uninstall command from %q", installCommand)
+}
+
+// lastArg returns the last non-flag argument after the subcommand
+func lastArg(fields []string) string {
+	for i := len(fields) - 1; i >= 2; i-- {
+		if !strings.HasPrefix(fields[i], "-") {
+			return fields[i]
+		}
+	}
+	return ""
+}
+
+func flagValue(fields []string, flag string) string {
+	for i, f := range fields {
+		if f == flag && i+1 < len(fields) {
+			return fields[i+1]
+		}
+		if value, ok := strings.CutPrefix(f, flag+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func flagPresent(fields []string, flag string) bool {
+	for _, f := range fields {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigPaths returns the existing configuration directories and files of a tool
+func ConfigPaths(tool *config.ToolDefinition) []string {
+	var paths []string
+	for _, raw := range tool.ConfigDirs {
+		path, err := expandPath(raw)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Lstat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// PurgeConfig deletes the configuration directories and files of a tool. Only paths
+// inside the home directory are deleted, never the home directory itself.
+func PurgeConfig(tool *config.ToolDefinition) ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, path := range ConfigPaths(tool) {
+		rel, err := filepath.Rel(home, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return removed, fmt.Errorf("refusing to delete %s outside the home directory", path)
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// expandPath expands a leading ~ and environment variables
+func expandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("config path %s is not absolute", path)
+	}
+	return filepath.Clean(path), nil
+}
@@ -1,8 +1,10 @@
 package manager
 
 import (
-	"bytes"
+	"context"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/jschneider/agenthelper/internal/config"
 	"github.com/jschneider/agenthelper/internal/platform"
@@ -16,16 +18,17 @@ type UpdateResult struct {
 	OldVersion  string
 	NewVersion  string
 	Output      string
+	Log         string // full output of the command that ran
 	Error       error
 	WasUpToDate bool
 }
 
-// Update updates a tool to the latest version
-func (m *Manager) Update(tool *config.ToolDefinition) *UpdateResult {
+// Update updates a tool to the latest version allowed by its version constraint
+func (m *Manager) Update(ctx context.Context, tool *config.ToolDefinition) *UpdateResult {
 	result := &UpdateResult{}
 
 	// Get current version
-	currentVersion, err := m.GetInstalledVersion(tool)
+	currentVersion, err := m.GetInstalledVersion(ctx, tool)
 	if err != nil {
 		return &UpdateResult{
 			Success: false,
@@ -34,9 +37,16 @@ func (m *Manager) Update(tool *config.ToolDefinition) *UpdateResult {
 	}
 	result.OldVersion = currentVersion
 
-	// Get latest version
-	latestVersion, err := GetLatestVersion(tool)
-	if err != nil {
+	// Get latest version allowed by the tool's version constraint
+	latestVersion, err := m.updateTarget(ctx, tool)
+	if err != nil && tool.Version != "" {
+		// Never update blindly when the result could fall outside the constraint
+		return &UpdateResult{
+			Success:    false,
+			OldVersion: currentVersion,
+			Error:      fmt.Errorf("could not resolve a version matching %s: %w", tool.Version, err),
+		}
+	} else if err != nil {
 		// If we can't get the latest version, try to update anyway
 		ui.Warn("Could not fetch latest version, attempting update anyway")
 	} else {
@@ -52,55 +62,62 @@ func (m *Manager) Update(tool *config.ToolDefinition) *UpdateResult {
 		}
 	}
 
-	// Get update command
-	method, command := m.GetBestInstallMethod(tool)
-	if method == "" {
+	method, command, err := m.updateCommand(ctx, tool, latestVersion)
+	if err != nil {
 		return &UpdateResult{
-			Success: false,
-			Error:   fmt.Errorf("no update method available for %s on %s", tool.Name, m.platform.String()),
+			Success:    false,
+			Method:     method,
+			OldVersion: currentVersion,
+			Error:      err,
 		}
 	}
 
-	// For npm packages, modify command to update
-	if method == "npm" {
-		// npm install -g already updates to latest
-	}
-
 	ui.Info("Updating %s using %s...", tool.Name, method)
 
-	// For winget, use upgrade command
-	if platform.IsWindows() && method == "winget" {
-		osKey := m.platform.GetOSKey()
-		if spec, ok := tool.Install[osKey]; ok && spec.WinGet != "" {
-			// Replace 'install' with 'upgrade' in the command
-			command = replaceWingetInstallWithUpgrade(spec.WinGet)
+	command = applyRegistry(tool, method, command)
+
+	if method == "download" {
+		local, cleanup, err := m.fetchDownload(ctx, tool, tool.Install[m.platform.GetOSKey()].Download, command)
+		if err != nil {
+			result.Method = method
+			result.Error = err
+			recordHistory(ctx, tool, "update", method, currentVersion, result.NewVersion, false)
+			return result
 		}
+		defer cleanup()
+		command = local
 	}
 
-	cmd := platform.NewShellCommand(command)
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	timeout := tool.InstallTimeout()
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	err = cmd.Run()
-	result.Output = stdout.String()
+	out, err := m.runToolCommand(runCtx, tool, "update", method, command)
+	result.Output = out.stdout.String()
+	result.Log = out.log.String()
 	result.Method = method
 
 	if err != nil {
 		result.Success = false
-		result.Error = fmt.Errorf("update failed: %w\n%s", err, stderr.String())
+		result.Error = fmt.Errorf("update failed: %w\n%s", contextError(runCtx, timeout, err), out.stderr.String())
+		recordHistory(ctx, tool, "update", method, currentVersion, result.NewVersion, false)
 		return result
 	}
 
 	// Verify update
-	newVersion, err := m.GetInstalledVersion(tool)
+	newVersion, err := m.GetInstalledVersion(ctx, tool)
 	if err != nil {
 		result.Success = false
 		result.Error = fmt.Errorf("update completed but could not verify: %w", err)
+		recordHistory(ctx, tool, "update", method, currentVersion, result.NewVersion, false)
 		return result
 	}
 
+	if !SameVersion(currentVersion, newVersion) {
+		recordHistory(ctx, tool, "update", method, currentVersion, newVersion, true)
+	}
+	// brew and apt may move the binary on upgrade
+	m.recordProvenance(tool, method)
 	result.Success = true
 	result.NewVersion = newVersion
 	if result.OldVersion == result.NewVersion {
@@ -113,24 +130,145 @@ func (m *Manager) Update(tool *config.ToolDefinition) *UpdateResult {
 	return result
 }
 
-// UpdateAll updates all installed tools
-func (m *Manager) UpdateAll() map[string]*UpdateResult {
+// updateTarget returns the version an update moves a tool to: the latest version allowed by
+// its constraint, or offline with a mirror the newest package of the method it is
+// installed with
+func (m *Manager) updateTarget(ctx context.Context, tool *config.ToolDefinition) (string, error) {
+	if m.useMirror() {
+		method, _ := m.InstalledMethod(ctx, tool)
+		pkg, err := m.findMirrorPackage(tool, method, "")
+		if err != nil {
+			return "", err
+		}
+		return pkg.Version, nil
+	}
+	return m.versions.LatestAllowedVersion(ctx, tool)
+}
+
+// updateCommand returns the method and command that update a tool to the given version.
+// The tool is updated with the method it is installed with, so that the update does not
+// install a second copy. The version may be empty when it could not be looked up.
+func (m *Manager) updateCommand(ctx context.Context, tool *config.ToolDefinition, version string) (string, string, error) {
+	method, command := m.InstalledMethod(ctx, tool)
+	if m.useMirror() {
+		method, command, _, err := m.mirrorInstallCommand(tool, method, version)
+		return method, command, err
+	}
+	if method == "" {
+		return "", "", fmt.Errorf("no update method available for %s on %s", tool.Name, m.platform.String())
+	}
+	if method == "script" && !m.scriptAllowed() {
+		return method, "", errScriptDisabled
+	}
+
+	// For winget, use upgrade command
+	if m.platform.OS == platform.Windows && method == "winget" {
+		osKey := m.platform.GetOSKey()
+		if spec, ok := tool.Install[osKey]; ok && spec.WinGet != "" {
+			// Replace 'install' with 'upgrade' in the command
+			command = replaceWingetInstallWithUpgrade(spec.WinGet)
+		}
+	}
+
+	// Pin the resolved version so the update cannot jump past the constraint or fall back
+	// from a pre-release channel to the default version
+	if tool.Version != "" || HasVersionPlaceholder(command) || tracksPrerelease(tool) {
+		if version == "" {
+			return method, "", fmt.Errorf("could not resolve the version to install for %s", tool.Name)
+		}
+		pinned, err := PinCommand(method, command, version)
+		if err != nil {
+			return method, "", fmt.Errorf("cannot pin version %s: %w", version, err)
+		}
+		command = pinned
+	}
+	return method, command, nil
+}
+
+// UpdateToVersion moves an installed tool to an exact version, which may also be older
+// than the installed one
+func (m *Manager) UpdateToVersion(ctx context.Context, tool *config.ToolDefinition, version string) *UpdateResult {
+	// The install of the exact version is logged as part of this update
+	ctx = withDefaultOperation(ctx, "update")
+
+	currentVersion, err := m.GetInstalledVersion(ctx, tool)
+	if err != nil {
+		return &UpdateResult{
+			Success: false,
+			Error:   fmt.Errorf("tool not installed: %w", err),
+		}
+	}
+
+	if SameVersion(currentVersion, version) {
+		return &UpdateResult{
+			Success:     true,
+			OldVersion:  currentVersion,
+			NewVersion:  currentVersion,
+			WasUpToDate: true,
+			Output:      fmt.Sprintf("%s is already at v%s", tool.Name, currentVersion),
+		}
+	}
+
+	method, _ := m.InstalledMethod(ctx, tool)
+	installResult := m.InstallVersion(ctx, tool, method, version)
+
+	result := &UpdateResult{
+		Success:    installResult.Success,
+		Method:     installResult.Method,
+		OldVersion: currentVersion,
+		NewVersion: version,
+		Log:        installResult.Log,
+		Error:      installResult.Error,
+	}
+	if result.Success {
+		result.Output = fmt.Sprintf("Successfully changed %s from v%s to v%s", tool.Name, currentVersion, version)
+	}
+	return result
+}
+
+// UpdateAll updates all installed tools, running up to the configured number of jobs at
+// a time and updating the dependencies of a tool before the tool itself
+func (m *Manager) UpdateAll(ctx context.Context) map[string]*UpdateResult {
 	results := make(map[string]*UpdateResult)
-	tools := config.GetAllTools()
+	var mu sync.Mutex
 
-	for _, tool := range tools {
-		t := tool // Create a copy for the closure
+	// Resolved up front, as the scheduler asks again whenever a tool waits for a lock
+	tools := config.GetAllTools()
+	methods := make(map[string]string, len(tools))
+	forEachTool(tools, statusConcurrency, func(idx int, tool *config.ToolDefinition) {
+		method, _ := m.InstalledMethod(ctx, tool)
+		mu.Lock()
+		methods[tool.Key] = method
+		mu.Unlock()
+	})
+	methodFor := func(tool *config.ToolDefinition) string {
+		return methods[tool.Key]
+	}
 
-		// Check if installed
-		if _, err := m.GetInstalledVersion(&t); err != nil {
-			results[t.Key] = &UpdateResult{
+	skipped := m.runScheduled(ctx, tools, methodFor, func(ctx context.Context, tool *config.ToolDefinition) error {
+		// Tools that are not installed don't hold back the tools depending on them
+		if _, err := m.GetInstalledVersion(ctx, tool); err != nil {
+			mu.Lock()
+			results[tool.Key] = &UpdateResult{
 				Success: false,
 				Error:   fmt.Errorf("not installed"),
 			}
-			continue
+			mu.Unlock()
+			return nil
 		}
 
-		results[t.Key] = m.Update(&t)
+		result := m.Update(ctx, tool)
+		mu.Lock()
+		results[tool.Key] = result
+		mu.Unlock()
+		return result.Error
+	})
+
+	for key, err := range skipped {
+		if ctx.Err() != nil && err == ctx.Err() {
+			continue // Not started because the run was cancelled
+		}
+		results[key] = &UpdateResult{Success: false, Error: err}
 	}
 
 	return results
@@ -138,9 +276,10 @@ func (m *Manager) UpdateAll() map[string]*UpdateResult {
 
 // replaceWingetInstallWithUpgrade converts a winget install command to upgrade
 func replaceWingetInstallWithUpgrade(installCmd string) string {
-	// Simple replacement - might need more sophisticated parsing
-	if len(installCmd) > 6 && installCmd[:6] == "winget" {
-		return "winget upgrade" + installCmd[14:] // Replace "winget install" with "winget upgrade"
+	fields := strings.Fields(installCmd)
+	if len(fields) < 2 || fields[0] != "winget" || fields[1] != "install" {
+		return installCmd
 	}
-	return installCmd
+	fields[1] = "upgrade"
+	return strings.Join(fields, " ")
 }
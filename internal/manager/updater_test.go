@@ -0,0 +1,267 @@
+package manager
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+	"github.com/jschneider/agenthelper/internal/platform/platformtest"
+)
+
+func TestReplaceWingetInstallWithUpgrade(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"winget install --id Anthropic.ClaudeCode -e", "winget upgrade --id Anthropic.ClaudeCode -e"},
+		{"winget install Microsoft.VisualStudioCode", "winget upgrade Microsoft.VisualStudioCode"},
+		{"winget  install   --id X", "winget upgrade --id X"},
+		{"winget install", "winget upgrade"},
+		{"winget upgrade --id X", "winget upgrade --id X"},
+		{"winget list", "winget list"},
+		{"winget", "winget"},
+		{"wingetx install X", "wingetx install X"},
+		{"npm install -g tool", "npm install -g tool"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := replaceWingetInstallWithUpgrade(tt.in); got != tt.want {
+			t.Errorf("replaceWingetInstallWithUpgrade(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	install := map[string]config.InstallSpec{
+		"windows": {WinGet: "winget install --id Tool.Tool -e", Npm: "npm install -g tool"},
+		"darwin":  {Brew: "brew install tool", Npm: "npm install -g tool"},
+		"linux":   {Npm: "npm install -g tool"},
+	}
+
+	tests := []struct {
+		name        string
+		os          platform.OS
+		executables []string
+		versions    string // published versions, oldest first
+		constraint  string
+		installed   string // empty when the tool is not installed
+		provenance  string // recorded install method, if any
+		wantCommand string // empty when no update command may run
+		wantNew     string
+		wantErr     string
+		upToDate    bool
+	}{
+		{
+			name: "windows upgrades with winget", os: platform.Windows, executables: []string{"winget", "npm"},
+			versions: "1.0.0,2.0.0", installed: "1.0.0",
+			wantCommand: "winget upgrade --id Tool.Tool -e", wantNew: "2.0.0",
+		},
+		{
+			name: "darwin updates with brew", os: platform.Darwin, executables: []string{"brew", "npm"},
+			versions: "1.0.0,2.0.0", installed: "1.0.0",
+			wantCommand: "brew install tool", wantNew: "2.0.0",
+		},
+		{
+			name: "linux updates with npm", os: platform.Linux, executables: []string{"npm"},
+			versions: "1.0.0,2.0.0", installed: "1.0.0",
+			wantCommand: "npm install -g tool", wantNew: "2.0.0",
+		},
+		{
+			name: "uses the method the tool was installed with", os: platform.Darwin, executables: []string{"brew", "npm"},
+			versions: "1.0.0,2.0.0", installed: "1.0.0", provenance: "npm",
+			wantCommand: "npm install -g tool", wantNew: "2.0.0",
+		},
+		{
+			name: "constraint pins the version", os: platform.Linux, executables: []string{"npm"},
+			versions: "1.2.0,1.2.5,1.3.0", constraint: "~1.2", installed: "1.2.0",
+			wantCommand: "npm install -g tool@1.2.5", wantNew: "1.2.5",
+		},
+		{
+			name: "already up to date", os: platform.Linux, executables: []string{"npm"},
+			versions: "1.0.0,2.0.0", installed: "2.0.0",
+			wantNew: "2.0.0", upToDate: true,
+		},
+		{
+			name: "not installed", os: platform.Linux, executables: []string{"npm"},
+			versions: "1.0.0",
+			wantErr:  "tool not installed",
+		},
+		{
+			name: "no version matching the constraint", os: platform.Linux, executables: []string{"npm"},
+			versions: "1.0.0,2.0.0", constraint: "^3", installed: "1.0.0",
+			wantErr: "could not resolve a version matching ^3",
+		},
+		{
+			name: "no method available", os: platform.Windows, executables: nil,
+			versions: "1.0.0,2.0.0", installed: "1.0.0",
+			wantErr: "no update method available",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, runner := newTestManager(t, tt.os, append(tt.executables, "tool")...)
+			tool := testTool("tool", install)
+			tool.VersionSource = config.VersionSource{Type: "test", Package: tt.versions}
+			tool.Version = tt.constraint
+
+			if tt.installed != "" {
+				runner.On("tool --version", platformtest.Response{Stdout: tt.installed + "\n"})
+			}
+			if tt.provenance != "" {
+				m.recordProvenance(&tool, tt.provenance)
+			}
+			if tt.wantCommand != "" {
+				runner.On(tt.wantCommand, platformtest.Response{Then: func() {
+					runner.On("tool --version", platformtest.Response{Stdout: tt.wantNew + "\n"})
+				}})
+			}
+
+			result := m.Update(context.Background(), &tool)
+
+			if tt.wantErr != "" {
+				if result.Success || result.Error == nil || !strings.Contains(result.Error.Error(), tt.wantErr) {
+					t.Fatalf("Update() = success %v, error %v; want error containing %q", result.Success, result.Error, tt.wantErr)
+				}
+			} else {
+				if !result.Success {
+					t.Fatalf("Update() failed: %v", result.Error)
+				}
+				if result.NewVersion != tt.wantNew || result.WasUpToDate != tt.upToDate {
+					t.Errorf("Update() = v%s, up to date %v; want v%s, up to date %v", result.NewVersion, result.WasUpToDate, tt.wantNew, tt.upToDate)
+				}
+			}
+
+			ran := changes(runner)
+			switch {
+			case tt.wantCommand == "" && len(ran) > 0:
+				t.Errorf("Update() ran %q, want no command", ran)
+			case tt.wantCommand != "" && (len(ran) != 1 || ran[0] != tt.wantCommand):
+				t.Errorf("Update() ran %q, want %q", ran, tt.wantCommand)
+			}
+		})
+	}
+}
+
+func TestUpdateRecordsProvenance(t *testing.T) {
+	m, runner := newTestManager(t, platform.Darwin, "brew", "npm", "tool")
+	tool := testTool("tool", map[string]config.InstallSpec{"darwin": {Brew: "brew install tool", Npm: "npm install -g tool"}})
+	tool.VersionSource = config.VersionSource{Type: "test", Package: "1.0.0,2.0.0"}
+	runner.On("tool --version", platformtest.Response{Stdout: "1.0.0\n"})
+	m.recordProvenance(&tool, "brew")
+
+	// The upgrade moves the binary into the keg of the new version
+	path := m.sys.Path.(platformtest.Path)
+	runner.On("brew install tool", platformtest.Response{Then: func() {
+		path["tool"] = []string{"/opt/homebrew/Cellar/tool/2.0.0/bin/tool"}
+		runner.On("tool --version", platformtest.Response{Stdout: "2.0.0\n"})
+	}})
+
+	if result := m.Update(context.Background(), &tool); !result.Success {
+		t.Fatalf("Update() failed: %v", result.Error)
+	}
+	p := m.Provenance(context.Background(), &tool)
+	if p == nil || p.Source != "install" || p.Method != "brew" || p.Path != path["tool"][0] {
+		t.Errorf("Provenance() = %+v, want the brew install at %s", p, path["tool"][0])
+	}
+}
+
+func TestUpdateAll(t *testing.T) {
+	npm := func(pkg string) map[string]config.InstallSpec {
+		return map[string]config.InstallSpec{"linux": {Npm: "npm install -g " + pkg}}
+	}
+	tool := func(key string, installed, latest string, dependsOn ...string) config.ToolDefinition {
+		t := testTool(key, npm(key))
+		t.VersionSource = config.VersionSource{Type: "test", Package: latest}
+		t.DependsOn = dependsOn
+		return t
+	}
+
+	tests := []struct {
+		name      string
+		tools     []config.ToolDefinition
+		installed map[string]string // installed versions; missing tools are not installed
+		failing   map[string]bool   // tools whose update command fails
+		wantOrder []string          // update commands, in the order they must run
+		wantOK    map[string]bool   // result success per tool
+	}{
+		{
+			name:      "updates outdated tools only",
+			tools:     []config.ToolDefinition{tool("a", "", "2.0.0"), tool("b", "", "1.0.0")},
+			installed: map[string]string{"a": "1.0.0", "b": "1.0.0"},
+			wantOrder: []string{"npm install -g a"},
+			wantOK:    map[string]bool{"a": true, "b": true},
+		},
+		{
+			name:      "skips tools that are not installed",
+			tools:     []config.ToolDefinition{tool("a", "", "2.0.0"), tool("b", "", "2.0.0")},
+			installed: map[string]string{"b": "1.0.0"},
+			wantOrder: []string{"npm install -g b"},
+			wantOK:    map[string]bool{"a": false, "b": true},
+		},
+		{
+			name:      "dependencies update first",
+			tools:     []config.ToolDefinition{tool("ext", "", "2.0.0", "host"), tool("host", "", "2.0.0")},
+			installed: map[string]string{"ext": "1.0.0", "host": "1.0.0"},
+			wantOrder: []string{"npm install -g host", "npm install -g ext"},
+			wantOK:    map[string]bool{"ext": true, "host": true},
+		},
+		{
+			name:      "failed dependency skips its dependents",
+			tools:     []config.ToolDefinition{tool("ext", "", "2.0.0", "host"), tool("host", "", "2.0.0")},
+			installed: map[string]string{"ext": "1.0.0", "host": "1.0.0"},
+			failing:   map[string]bool{"host": true},
+			wantOrder: []string{"npm install -g host"},
+			wantOK:    map[string]bool{"ext": false, "host": false},
+		},
+		{
+			name:      "missing dependency does not block its dependents",
+			tools:     []config.ToolDefinition{tool("ext", "", "2.0.0", "host"), tool("host", "", "2.0.0")},
+			installed: map[string]string{"ext": "1.0.0"},
+			wantOrder: []string{"npm install -g ext"},
+			wantOK:    map[string]bool{"ext": true, "host": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, runner := newTestManager(t, platform.Linux, "npm")
+			setTools(t, tt.tools...)
+
+			for _, tool := range tt.tools {
+				key := tool.Key
+				if v, ok := tt.installed[key]; ok {
+					runner.On(key+" --version", platformtest.Response{Stdout: v + "\n"})
+				}
+				exit := 0
+				if tt.failing[key] {
+					exit = 1
+				}
+				runner.On("npm install -g "+key, platformtest.Response{ExitCode: exit, Then: func() {
+					if exit == 0 {
+						runner.On(key+" --version", platformtest.Response{Stdout: "2.0.0\n"})
+					}
+				}})
+			}
+
+			results := m.UpdateAll(context.Background())
+
+			for key, want := range tt.wantOK {
+				r, ok := results[key]
+				if !ok {
+					t.Errorf("UpdateAll() has no result for %s", key)
+					continue
+				}
+				if r.Success != want {
+					t.Errorf("UpdateAll() %s success = %v (%v), want %v", key, r.Success, r.Error, want)
+				}
+			}
+			if got := runner.CallsMatching("npm "); strings.Join(got, "; ") != strings.Join(tt.wantOrder, "; ") {
+				t.Errorf("UpdateAll() ran %q, want %q", got, tt.wantOrder)
+			}
+		})
+	}
+}
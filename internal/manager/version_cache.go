@@ -0,0 +1,150 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jschneider/agenthelper/internal/platform"
+)
+
+// DefaultCacheTTL is how long a cached version lookup is used without revalidation
+const DefaultCacheTTL = time.Hour
+
+// cacheEntry is a cached response of a version source
+type cacheEntry struct {
+	URL       string    `json:"url"`
+	ETag      string    `json:"etag,omitempty"`
+	Body      []byte    `json:"body"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// cachedGet performs a request through the on-disk version cache. Fresh entries are served
+// without network access, stale ones are revalidated with If-None-Match, and the last known
+// response is returned when the source is unreachable or rate limited.
+func (c *VersionChecker) cachedGet(req *http.Request) ([]byte, int, error) {
+	key := req.URL.String() + "|" + req.Header.Get("Accept")
+	entry := loadCacheEntry(key)
+
+	if entry != nil && !c.Refresh && time.Since(entry.FetchedAt) < c.CacheTTL {
+		return entry.Body, http.StatusOK, nil
+	}
+	if c.offline() {
+		// A stale answer beats none, and waiting on a network that is not there
+		if entry != nil {
+			return entry.Body, http.StatusOK, nil
+		}
+		return nil, 0, ErrOffline
+	}
+	if entry != nil && entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		// A cancelled operation should stop, not fall back to the cache
+		if entry != nil && req.Context().Err() != context.Canceled {
+			return entry.Body, http.StatusOK, nil
+		}
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		entry.FetchedAt = time.Now()
+		saveCacheEntry(key, entry)
+		return entry.Body, http.StatusOK, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		saveCacheEntry(key, &cacheEntry{
+			URL:       req.URL.String(),
+			ETag:      resp.Header.Get("ETag"),
+			Body:      body,
+			FetchedAt: time.Now(),
+		})
+	case entry != nil && isTransientStatus(resp.StatusCode):
+		return entry.Body, http.StatusOK, nil
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+// isTransientStatus reports whether a status means the source is temporarily unavailable
+func isTransientStatus(status int) bool {
+	return status == http.StatusForbidden || status == http.StatusTooManyRequests || status >= 500
+}
+
+func cacheDir() string {
+	paths, err := platform.GetPaths()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(paths.CacheDir, "versions")
+}
+
+func cacheFile(key string) string {
+	dir := cacheDir()
+	if dir == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+func loadCacheEntry(key string) *cacheEntry {
+	path := cacheFile(key)
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// saveCacheEntry writes an entry atomically; failures only cost a future cache miss
+func saveCacheEntry(key string, entry *cacheEntry) {
+	path := cacheFile(key)
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "entry-*.tmp")
+	if err != nil {
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
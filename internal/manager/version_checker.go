@@ -1,15 +1,18 @@
 package manager
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
 )
 
 // VersionInfo holds version information for a tool
@@ -19,54 +22,196 @@ type VersionInfo struct {
 	HasUpdate bool
 }
 
-// HTTPClient for making API requests
-var httpClient = &http.Client{
-	Timeout: 10 * time.Second,
+// Default endpoints of the version sources without a package manager configuration to
+// read them from
+const (
+	defaultCratesAPI   = "https://crates.io"
+	defaultHomebrewAPI = "https://formulae.brew.sh/api"
+	defaultVSCodeAPI   = "https://update.code.visualstudio.com"
+)
+
+// VersionEndpoints are the keys of VersionChecker.BaseURLs. github also serves the
+// github-tags and winget-pkgs sources.
+var VersionEndpoints = []string{"npm", "pypi", "github", "crates", "homebrew", "vscode-update"}
+
+// VersionSourceURLs returns the endpoints of version sources configured for all tools,
+// e.g. mirrors of registry.npmjs.org or pypi.org, for Options.VersionSources. A registry on
+// a tool's version source still takes precedence. Unknown endpoints are left out and
+// reported in the error.
+func VersionSourceURLs(baseURLs map[string]string) (map[string]string, error) {
+	urls := make(map[string]string, len(baseURLs))
+	var unknown []string
+	for endpoint, baseURL := range baseURLs {
+		if !isVersionEndpoint(endpoint) {
+			unknown = append(unknown, endpoint)
+			continue
+		}
+		urls[endpoint] = baseURL
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return urls, fmt.Errorf("unknown version sources %s, expected one of %s", strings.Join(unknown, ", "), strings.Join(VersionEndpoints, ", "))
+	}
+	return urls, nil
+}
+
+func isVersionEndpoint(name string) bool {
+	for _, endpoint := range VersionEndpoints {
+		if endpoint == name {
+			return true
+		}
+	}
+	return false
+}
+
+// VersionChecker looks up published versions of tools. Requests go through the on-disk
+// version cache.
+type VersionChecker struct {
+	// Client sends the requests to version sources. Requests are bounded by their
+	// context, see config.ToolDefinition.CheckTimeout.
+	Client *http.Client
+	// BaseURLs replaces the default endpoint per key of VersionEndpoints
+	BaseURLs map[string]string
+	// Runner runs the commands of command sources
+	Runner platform.Runner
+	// Offline reports whether lookups are answered from the cache only; nil means online
+	Offline func() bool
+	// CacheTTL is how long a cached response is used without revalidation. Zero always
+	// revalidates but still falls back to the cache when offline.
+	CacheTTL time.Duration
+	// Refresh bypasses cached responses
+	Refresh bool
+
+	tokens githubTokens
+}
+
+// NewVersionChecker returns a checker for the version sources and cache settings of opts
+func NewVersionChecker(client *http.Client, runner platform.Runner, opts Options) *VersionChecker {
+	return &VersionChecker{
+		Client:   client,
+		BaseURLs: opts.VersionSources,
+		Runner:   runner,
+		Offline:  opts.Offline,
+		CacheTTL: opts.CacheTTL,
+		Refresh:  opts.Refresh,
+	}
+}
+
+// offline reports whether the checker must not reach the network
+func (c *VersionChecker) offline() bool {
+	return c.Offline != nil && c.Offline()
+}
+
+// baseURL returns the configured base URL of an endpoint, or "" for the default
+func (c *VersionChecker) baseURL(endpoint string) string {
+	return c.BaseURLs[endpoint]
+}
+
+// endpoint returns the base URL of a source without package manager configuration: the
+// source's registry, then the configured base URL, then the default
+func (c *VersionChecker) endpoint(source config.VersionSource, endpoint, fallback string) string {
+	if source.Registry != "" {
+		return strings.TrimSuffix(source.Registry, "/")
+	}
+	if baseURL := c.baseURL(endpoint); baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/")
+	}
+	return fallback
 }
 
-// GetLatestVersion fetches the latest version for a tool based on its version source
-func GetLatestVersion(tool *config.ToolDefinition) (string, error) {
-	switch tool.VersionSource.Type {
-	case "npm":
-		return getLatestNpmVersion(tool.VersionSource.Package)
-	case "github":
-		return getLatestGitHubVersion(tool.VersionSource.Owner, tool.VersionSource.Repo)
-	case "pypi":
-		return getLatestPyPIVersion(tool.VersionSource.Package)
-	case "vscode-update":
-		return getLatestVSCodeVersion(tool.VersionSource.Channel)
-	case "cursor-todesktop":
-		return getLatestCursorVersion()
-	case "winget-pkgs":
-		return getLatestWingetPkgsVersion(tool.VersionSource.Package)
-	default:
+// LatestVersion fetches the latest version for a tool based on its version source
+func (c *VersionChecker) LatestVersion(ctx context.Context, tool *config.ToolDefinition) (string, error) {
+	provider, ok := GetVersionProvider(tool.VersionSource.Type)
+	if !ok {
 		return "", fmt.Errorf("unknown version source type: %s", tool.VersionSource.Type)
 	}
+
+	ctx, cancel := context.WithTimeout(ctx, tool.CheckTimeout())
+	defer cancel()
+	return provider.Latest(ctx, c, tool.VersionSource)
+}
+
+// AvailableVersions lists the published versions of a tool. Sources that cannot list
+// versions return only the latest version.
+func (c *VersionChecker) AvailableVersions(ctx context.Context, tool *config.ToolDefinition) ([]string, error) {
+	provider, ok := GetVersionProvider(tool.VersionSource.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown version source type: %s", tool.VersionSource.Type)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, tool.CheckTimeout())
+	defer cancel()
+	if lister, ok := provider.(VersionLister); ok {
+		return lister.Versions(ctx, c, tool.VersionSource)
+	}
+
+	latest, err := provider.Latest(ctx, c, tool.VersionSource)
+	if err != nil {
+		return nil, err
+	}
+	return []string{latest}, nil
+}
+
+// LatestAllowedVersion returns the highest available version that satisfies the
+// tool's version constraint, or the latest version if no constraint is configured
+func (c *VersionChecker) LatestAllowedVersion(ctx context.Context, tool *config.ToolDefinition) (string, error) {
+	if tool.Version == "" {
+		return c.LatestVersion(ctx, tool)
+	}
+
+	constraint, err := semver.NewConstraint(tool.Version)
+	if err != nil {
+		return "", fmt.Errorf("invalid version constraint %q for %s: %w", tool.Version, tool.Key, err)
+	}
+
+	versions, err := c.AvailableVersions(ctx, tool)
+	if err != nil {
+		return "", err
+	}
+
+	var best *semver.Version
+	var bestRaw string
+	for _, raw := range versions {
+		v, err := semver.NewVersion(strings.TrimPrefix(raw, "v"))
+		if err != nil || !constraint.Check(v) {
+			continue
+		}
+		if best == nil || v.GreaterThan(best) {
+			best = v
+			bestRaw = raw
+		}
+	}
+
+	if best == nil {
+		return "", fmt.Errorf("no version of %s matches constraint %s", tool.Name, tool.Version)
+	}
+
+	return strings.TrimPrefix(bestRaw, "v"), nil
 }
 
 // NpmPackageInfo represents npm registry response
 type NpmPackageInfo struct {
-	DistTags struct {
-		Latest string `json:"latest"`
-	} `json:"dist-tags"`
+	DistTags map[string]string `json:"dist-tags"`
 }
 
-func getLatestNpmVersion(packageName string) (string, error) {
-	url := fmt.Sprintf("https://registry.npmjs.org/%s", packageName)
+// latestNpmVersion returns the version of a dist-tag, "latest" if tag is empty
+func (c *VersionChecker) latestNpmVersion(ctx context.Context, registry, packageName, tag string) (string, error) {
+	if tag == "" {
+		tag = "latest"
+	}
 
-	resp, err := httpClient.Get(url)
+	req, err := newNpmRequest(ctx, registry, packageName)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch npm version: %w", err)
+		return "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("npm registry returned status %d", resp.StatusCode)
+	body, status, err := c.cachedGet(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch npm version: %w", err)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	if status != http.StatusOK {
+		return "", fmt.Errorf("npm registry returned status %d", status)
 	}
 
 	var info NpmPackageInfo
@@ -74,37 +219,91 @@ func getLatestNpmVersion(packageName string) (string, error) {
 		return "", fmt.Errorf("failed to parse npm response: %w", err)
 	}
 
-	return info.DistTags.Latest, nil
+	version, ok := info.DistTags[tag]
+	if !ok {
+		return "", fmt.Errorf("npm package %s has no dist-tag %q", packageName, tag)
+	}
+	return version, nil
+}
+
+// NpmVersionList represents the abbreviated npm registry response
+type NpmVersionList struct {
+	Versions map[string]json.RawMessage `json:"versions"`
+}
+
+func (c *VersionChecker) npmVersions(ctx context.Context, registry, packageName string) ([]string, error) {
+	req, err := newNpmRequest(ctx, registry, packageName)
+	if err != nil {
+		return nil, err
+	}
+	// The abbreviated document only carries what is needed to resolve versions
+	req.Header.Set("Accept", "application/vnd.npm.install-v1+json")
+
+	body, status, err := c.cachedGet(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch npm versions: %w", err)
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("npm registry returned status %d", status)
+	}
+
+	var list NpmVersionList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse npm response: %w", err)
+	}
+
+	versions := make([]string, 0, len(list.Versions))
+	for version := range list.Versions {
+		versions = append(versions, version)
+	}
+	return versions, nil
 }
 
 // GitHubRelease represents GitHub release API response
 type GitHubRelease struct {
-	TagName string `json:"tag_name"`
-	Name    string `json:"name"`
+	TagName    string        `json:"tag_name"`
+	Name       string        `json:"name"`
+	Draft      bool          `json:"draft"`
+	Prerelease bool          `json:"prerelease"`
+	Assets     []GitHubAsset `json:"assets"`
 }
 
-func getLatestGitHubVersion(owner, repo string) (string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", owner, repo)
+// GitHubAsset is a file attached to a GitHub release
+type GitHubAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// latestGitHubVersion returns the latest release. releases/latest never returns
+// prereleases, so with prerelease the newest published entry of the release list is used.
+func (c *VersionChecker) latestGitHubVersion(ctx context.Context, apiBase, owner, repo string, prerelease bool) (string, error) {
+	if prerelease {
+		// The release list is ordered newest first
+		versions, err := c.gitHubVersions(ctx, apiBase, owner, repo)
+		if err != nil {
+			return "", err
+		}
+		if len(versions) == 0 {
+			return "", fmt.Errorf("no releases found in %s/%s", owner, repo)
+		}
+		return versions[0], nil
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/releases/latest", apiBase, owner, repo)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := c.newGitHubRequest(ctx, url)
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
 
-	resp, err := httpClient.Do(req)
+	body, status, err := c.cachedGet(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch GitHub version: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	if status != http.StatusOK {
+		return "", fmt.Errorf("GitHub API returned status %d", status)
 	}
 
 	var release GitHubRelease
@@ -117,29 +316,125 @@ func getLatestGitHubVersion(owner, repo string) (string, error) {
 	return version, nil
 }
 
+// GitHub lists releases in pages; it does not list more than 1000 releases of a repository
+const (
+	gitHubPageSize = 100
+	gitHubMaxPages = 10
+)
+
+// gitHubReleases calls visit with each published release, newest first, fetching further
+// pages until visit returns false or there are no more releases
+func (c *VersionChecker) gitHubReleases(ctx context.Context, apiBase, owner, repo string, visit func(GitHubRelease) bool) error {
+	for page := 1; page <= gitHubMaxPages; page++ {
+		url := fmt.Sprintf("%s/repos/%s/%s/releases?per_page=%d&page=%d", apiBase, owner, repo, gitHubPageSize, page)
+
+		req, err := c.newGitHubRequest(ctx, url)
+		if err != nil {
+			return err
+		}
+
+		body, status, err := c.cachedGet(req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch GitHub releases: %w", err)
+		}
+
+		if status != http.StatusOK {
+			return fmt.Errorf("GitHub API returned status %d", status)
+		}
+
+		var releases []GitHubRelease
+		if err := json.Unmarshal(body, &releases); err != nil {
+			return fmt.Errorf("failed to parse GitHub response: %w", err)
+		}
+
+		for _, release := range releases {
+			if !release.Draft && !visit(release) {
+				return nil
+			}
+		}
+		if len(releases) < gitHubPageSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (c *VersionChecker) gitHubVersions(ctx context.Context, apiBase, owner, repo string) ([]string, error) {
+	var versions []string
+	err := c.gitHubReleases(ctx, apiBase, owner, repo, func(release GitHubRelease) bool {
+		versions = append(versions, strings.TrimPrefix(release.TagName, "v"))
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// gitHubReleaseAssets returns the files attached to the release of a version
+func (c *VersionChecker) gitHubReleaseAssets(ctx context.Context, apiBase, owner, repo, version string) ([]GitHubAsset, error) {
+	var assets []GitHubAsset
+	found := false
+	err := c.gitHubReleases(ctx, apiBase, owner, repo, func(release GitHubRelease) bool {
+		if SameVersion(release.TagName, version) {
+			assets, found = release.Assets, true
+		}
+		return !found
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no release %s found in %s/%s", version, owner, repo)
+	}
+	return assets, nil
+}
+
 // PyPIPackageInfo represents PyPI API response
 type PyPIPackageInfo struct {
 	Info struct {
 		Version string `json:"version"`
 	} `json:"info"`
+	Releases map[string]json.RawMessage `json:"releases"`
 }
 
-func getLatestPyPIVersion(packageName string) (string, error) {
-	url := fmt.Sprintf("https://pypi.org/pypi/%s/json", packageName)
+// latestPyPIVersion returns the latest stable release, or with prerelease the highest
+// release including alpha, beta, rc and dev versions
+func (c *VersionChecker) latestPyPIVersion(ctx context.Context, index, packageName string, prerelease bool) (string, error) {
+	if prerelease {
+		versions, err := c.pyPIVersions(ctx, index, packageName)
+		if err != nil {
+			return "", err
+		}
+		var best *semver.Version
+		var bestRaw string
+		for _, raw := range versions {
+			v, err := semver.NewVersion(pep440ToSemver(raw))
+			if err != nil {
+				continue
+			}
+			if best == nil || v.GreaterThan(best) {
+				best, bestRaw = v, raw
+			}
+		}
+		if best == nil {
+			return "", fmt.Errorf("no releases found for %s", packageName)
+		}
+		return bestRaw, nil
+	}
 
-	resp, err := httpClient.Get(url)
+	req, err := newPyPIRequest(ctx, index, packageName)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch PyPI version: %w", err)
+		return "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("PyPI returned status %d", resp.StatusCode)
+	body, status, err := c.cachedGet(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch PyPI version: %w", err)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	if status != http.StatusOK {
+		return "", fmt.Errorf("PyPI returned status %d", status)
 	}
 
 	var info PyPIPackageInfo
@@ -150,6 +445,54 @@ func getLatestPyPIVersion(packageName string) (string, error) {
 	return info.Info.Version, nil
 }
 
+func (c *VersionChecker) pyPIVersions(ctx context.Context, index, packageName string) ([]string, error) {
+	req, err := newPyPIRequest(ctx, index, packageName)
+	if err != nil {
+		return nil, err
+	}
+
+	body, status, err := c.cachedGet(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch PyPI versions: %w", err)
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("PyPI returned status %d", status)
+	}
+
+	var info PyPIPackageInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse PyPI response: %w", err)
+	}
+
+	versions := make([]string, 0, len(info.Releases))
+	for version, files := range info.Releases {
+		// Releases without files were deleted or never uploaded
+		if string(files) == "[]" {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+var pep440Pre = regexp.MustCompile(`^(\d+(?:\.\d+)*)[.-]?(a|alpha|b|beta|rc|c|dev)[.-]?(\d*)$`)
+
+// pep440ToSemver converts PEP 440 versions such as 1.2.0rc1 or 1.2.0.dev3 to semver,
+// so pre-releases order correctly before their final release. Dev releases map to 0dev,
+// which sorts before alpha, as PEP 440 orders them.
+func pep440ToSemver(version string) string {
+	m := pep440Pre.FindStringSubmatch(strings.ToLower(version))
+	if m == nil {
+		return version
+	}
+	label := map[string]string{"a": "alpha", "alpha": "alpha", "b": "beta", "beta": "beta", "rc": "rc", "c": "rc", "dev": "0dev"}[m[2]]
+	if m[3] == "" {
+		m[3] = "0"
+	}
+	return m[1] + "-" + label + "." + m[3]
+}
+
 // ExtractVersion extracts version from command output using regex pattern
 func ExtractVersion(output, pattern string) string {
 	if pattern == "" {
@@ -177,25 +520,24 @@ type VSCodeUpdateInfo struct {
 	Name           string `json:"name"`
 }
 
-func getLatestVSCodeVersion(channel string) (string, error) {
+func (c *VersionChecker) latestVSCodeVersion(ctx context.Context, apiBase, channel string) (string, error) {
 	if channel == "" {
 		channel = "stable"
 	}
-	url := fmt.Sprintf("https://update.code.visualstudio.com/api/update/win32-x64-user/%s/latest", channel)
+	url := fmt.Sprintf("%s/api/update/win32-x64-user/%s/latest", apiBase, channel)
 
-	resp, err := httpClient.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch VS Code version: %w", err)
+		return "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("VS Code update API returned status %d", resp.StatusCode)
+	body, status, err := c.cachedGet(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch VS Code version: %w", err)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	if status != http.StatusOK {
+		return "", fmt.Errorf("VS Code update API returned status %d", status)
 	}
 
 	var info VSCodeUpdateInfo
@@ -208,66 +550,28 @@ func getLatestVSCodeVersion(channel string) (string, error) {
 	return version, nil
 }
 
-func getLatestCursorVersion() (string, error) {
-	url := "https://download.todesktop.com/230313mzl4w4u92/latest.yml"
-
-	resp, err := httpClient.Get(url)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch Cursor version: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Cursor API returned status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Parse YAML manually - look for "version: X.Y.Z"
-	lines := strings.Split(string(body), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "version:") {
-			version := strings.TrimPrefix(line, "version:")
-			return strings.TrimSpace(version), nil
-		}
-	}
-
-	return "", fmt.Errorf("could not find version in Cursor response")
-}
-
 // WingetPkgsEntry represents a directory entry from GitHub API
 type WingetPkgsEntry struct {
 	Name string `json:"name"`
 	Type string `json:"type"`
 }
 
-func getLatestWingetPkgsVersion(packagePath string) (string, error) {
+func (c *VersionChecker) latestWingetPkgsVersion(ctx context.Context, apiBase, packagePath string) (string, error) {
 	// packagePath format: "w/Warp/Warp" or "m/Microsoft/VisualStudioCode"
-	url := fmt.Sprintf("https://api.github.com/repos/microsoft/winget-pkgs/contents/manifests/%s", packagePath)
+	url := fmt.Sprintf("%s/repos/microsoft/winget-pkgs/contents/manifests/%s", apiBase, packagePath)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := c.newGitHubRequest(ctx, url)
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
 
-	resp, err := httpClient.Do(req)
+	body, status, err := c.cachedGet(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch winget-pkgs version: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("winget-pkgs API returned status %d", resp.StatusCode)
-	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	if status != http.StatusOK {
+		return "", fmt.Errorf("winget-pkgs API returned status %d", status)
 	}
 
 	var entries []WingetPkgsEntry
@@ -275,13 +579,14 @@ func getLatestWingetPkgsVersion(packagePath string) (string, error) {
 		return "", fmt.Errorf("failed to parse winget-pkgs response: %w", err)
 	}
 
-	// Find the latest version (last directory entry that looks like a version)
-	var latestVersion string
+	// Directory entries are sorted by name, so 1.9 comes after 1.22; compare numerically
+	var versions []string
 	for _, entry := range entries {
-		if entry.Type == "dir" && (strings.HasPrefix(entry.Name, "v") || regexp.MustCompile(`^\d`).MatchString(entry.Name)) {
-			latestVersion = entry.Name
+		if entry.Type == "dir" && looksLikeVersion(strings.TrimPrefix(entry.Name, "v")) {
+			versions = append(versions, entry.Name)
 		}
 	}
+	latestVersion := newestVersion(versions)
 
 	if latestVersion == "" {
 		return "", fmt.Errorf("no version found in winget-pkgs")
@@ -0,0 +1,138 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jschneider/agenthelper/internal/config"
+)
+
+// VersionPlaceholder can be used in install commands to template the version to install
+const VersionPlaceholder = "{{version}}"
+
+// PinCommand rewrites an install command so that it installs exactly the given version.
+// Commands containing VersionPlaceholder are templated directly; otherwise only the first
+// segment of a chained command (cmd1 && cmd2) is rewritten, since that is the one
+// invoking the package manager.
+func PinCommand(method, command, version string) (string, error) {
+	version = strings.TrimPrefix(version, "v")
+	if version == "" {
+		return command, nil
+	}
+
+	if HasVersionPlaceholder(command) {
+		return strings.ReplaceAll(command, VersionPlaceholder, version), nil
+	}
+
+	first, rest := command, ""
+	if idx := strings.Index(command, "&&"); idx >= 0 {
+		first, rest = command[:idx], command[idx:]
+	}
+	first = strings.TrimSpace(first)
+
+	var pinned string
+	switch method {
+	case "npm":
+		pinned = pinPackageArg(first, func(pkg string) string {
+			// Keep the scope '@' of scoped packages, drop any existing version suffix
+			if idx := strings.LastIndex(pkg, "@"); idx > 0 {
+				pkg = pkg[:idx]
+			}
+			return pkg + "@" + version
+		})
+	case "pip":
+		pinned = pinPackageArg(first, func(pkg string) string {
+			if idx := strings.IndexAny(pkg, "=<>~!"); idx > 0 {
+				pkg = pkg[:idx]
+			}
+			return pkg + "==" + version
+		})
+	case "winget":
+		pinned = removeFlag(first, "--version") + " --version " + version
+	case "apt":
+		pinned = pinPackageArg(first, func(pkg string) string {
+			if idx := strings.Index(pkg, "="); idx > 0 {
+				pkg = pkg[:idx]
+			}
+			return pkg + "=" + version
+		})
+	case "brew":
+		// brew installs only the current version of a formula; versioned formulae such as
+		// tool@2 exist for a few major versions and still not at an exact version
+		return "", fmt.Errorf("brew cannot install a specific version; for a versioned formula add a %s placeholder, e.g. brew install tool@%s", VersionPlaceholder, VersionPlaceholder)
+	default:
+		return "", fmt.Errorf("installing a specific version is not supported with %s; add a %s placeholder to the install command", method, VersionPlaceholder)
+	}
+
+	if pinned == first {
+		return "", fmt.Errorf("could not find a package name in %q", first)
+	}
+
+	if rest != "" {
+		return pinned + " " + rest, nil
+	}
+	return pinned, nil
+}
+
+// HasVersionPlaceholder reports whether a command templates the version to install
+func HasVersionPlaceholder(command string) bool {
+	return strings.Contains(command, VersionPlaceholder)
+}
+
+// tracksPrerelease reports whether a tool follows a pre-release channel, whose versions a
+// package manager would not pick on its own, so installs and updates must be pinned
+func tracksPrerelease(tool *config.ToolDefinition) bool {
+	source := tool.VersionSource
+	if source.Prerelease {
+		return true
+	}
+	return source.Type == "npm" && source.Channel != "" && source.Channel != "latest"
+}
+
+// ParseToolVersion splits a "tool@version" argument into its key and version
+func ParseToolVersion(arg string) (string, string) {
+	if idx := strings.LastIndex(arg, "@"); idx > 0 {
+		return arg[:idx], strings.TrimPrefix(arg[idx+1:], "v")
+	}
+	return arg, ""
+}
+
+// pinPackageArg applies pin to the last non-flag argument of a command
+func pinPackageArg(command string, pin func(string) string) string {
+	fields := strings.Fields(command)
+	for i := len(fields) - 1; i >= 2; i-- {
+		if strings.HasPrefix(fields[i], "-") {
+			continue
+		}
+		fields[i] = pin(fields[i])
+		return strings.Join(fields, " ")
+	}
+	return command
+}
+
+// removeFlag drops a flag and its value from a command
+func removeFlag(command, flag string) string {
+	fields := strings.Fields(command)
+	out := make([]string, 0, len(fields))
+	for i := 0; i < len(fields); i++ {
+		if fields[i] == flag {
+			i++ // Skip the value as well
+			continue
+		}
+		if strings.HasPrefix(fields[i], flag+"=") {
+			continue
+		}
+		out = append(out, fields[i])
+	}
+	return strings.Join(out, " ")
+}
+
+// normalizeVersion strips a leading 'v' so versions from different sources compare equal
+func normalizeVersion(version string) string {
+	return strings.TrimPrefix(strings.TrimSpace(version), "v")
+}
+
+// SameVersion reports whether two version strings refer to the same version
+func SameVersion(a, b string) bool {
+	return normalizeVersion(a) == normalizeVersion(b)
+}
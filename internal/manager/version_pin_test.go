@@ -0,0 +1,37 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPinCommand(t *testing.T) {
+	tests := []struct {
+		method  string
+		command string
+		want    string
+		wantErr string
+	}{
+		{"npm", "npm install -g @scope/tool@1.0.0", "npm install -g @scope/tool@2.3.0", ""},
+		{"pip", "pipx install tool && tool --setup", "pipx install tool==2.3.0 && tool --setup", ""},
+		{"winget", "winget install --id Tool.Tool -e --version 1.0", "winget install --id Tool.Tool -e --version 2.3.0", ""},
+		{"apt", "sudo apt install -y tool", "sudo apt install -y tool=2.3.0", ""},
+		{"brew", "brew install tool@{{version}}", "brew install tool@2.3.0", ""},
+		{"brew", "brew install tool", "", "brew cannot install a specific version"},
+		{"brew", "brew install --cask tool", "", "brew cannot install a specific version"},
+		{"script", "curl -fsSL https://example.com/install.sh | sh", "", "not supported with script"},
+	}
+
+	for _, tt := range tests {
+		got, err := PinCommand(tt.method, tt.command, "v2.3.0")
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("PinCommand(%s, %q) = %q, %v; want error %q", tt.method, tt.command, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("PinCommand(%s, %q) = %q, %v; want %q", tt.method, tt.command, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,486 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+)
+
+// VersionProvider resolves the latest version of a version_source type
+type VersionProvider interface {
+	Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error)
+}
+
+// VersionLister is implemented by providers that can list every published version,
+// which is needed to resolve version constraints
+type VersionLister interface {
+	Versions(ctx context.Context, c *VersionChecker, source config.VersionSource) ([]string, error)
+}
+
+var versionProviders = make(map[string]VersionProvider)
+
+// RegisterVersionProvider makes a provider available for a version_source type.
+// New types must also be added to config.VersionSourceTypes to pass validation.
+func RegisterVersionProvider(sourceType string, provider VersionProvider) {
+	versionProviders[sourceType] = provider
+}
+
+// GetVersionProvider returns the provider registered for a version_source type
+func GetVersionProvider(sourceType string) (VersionProvider, bool) {
+	provider, ok := versionProviders[sourceType]
+	return provider, ok
+}
+
+func init() {
+	RegisterVersionProvider("npm", npmProvider{})
+	RegisterVersionProvider("github", githubReleasesProvider{})
+	RegisterVersionProvider("github-tags", githubTagsProvider{})
+	RegisterVersionProvider("pypi", pypiProvider{})
+	RegisterVersionProvider("crates", cratesProvider{})
+	RegisterVersionProvider("homebrew", homebrewProvider{})
+	RegisterVersionProvider("vscode-update", vscodeProvider{})
+	RegisterVersionProvider("winget-pkgs", wingetPkgsProvider{})
+	RegisterVersionProvider("http-json", httpJSONProvider{})
+	RegisterVersionProvider("http-regex", httpRegexProvider{})
+	RegisterVersionProvider("command", commandProvider{})
+}
+
+type npmProvider struct{}
+
+func (npmProvider) Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error) {
+	return c.latestNpmVersion(ctx, c.npmRegistry(source), source.Package, source.Channel)
+}
+
+func (npmProvider) Versions(ctx context.Context, c *VersionChecker, source config.VersionSource) ([]string, error) {
+	return c.npmVersions(ctx, c.npmRegistry(source), source.Package)
+}
+
+type githubReleasesProvider struct{}
+
+func (githubReleasesProvider) Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error) {
+	return c.latestGitHubVersion(ctx, c.githubAPIBase(source), source.Owner, source.Repo, source.Prerelease)
+}
+
+func (githubReleasesProvider) Versions(ctx context.Context, c *VersionChecker, source config.VersionSource) ([]string, error) {
+	return c.gitHubVersions(ctx, c.githubAPIBase(source), source.Owner, source.Repo)
+}
+
+type pypiProvider struct{}
+
+func (pypiProvider) Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error) {
+	return c.latestPyPIVersion(ctx, c.pypiIndex(source), source.Package, source.Prerelease)
+}
+
+func (pypiProvider) Versions(ctx context.Context, c *VersionChecker, source config.VersionSource) ([]string, error) {
+	return c.pyPIVersions(ctx, c.pypiIndex(source), source.Package)
+}
+
+type vscodeProvider struct{}
+
+func (vscodeProvider) Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error) {
+	return c.latestVSCodeVersion(ctx, c.endpoint(source, "vscode-update", defaultVSCodeAPI), source.Channel)
+}
+
+type wingetPkgsProvider struct{}
+
+func (wingetPkgsProvider) Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error) {
+	return c.latestWingetPkgsVersion(ctx, c.githubAPIBase(source), source.Package)
+}
+
+// githubTagsProvider covers repositories that tag versions without publishing releases
+type githubTagsProvider struct{}
+
+// GitHubTag represents an entry of the GitHub tags API response
+type GitHubTag struct {
+	Name string `json:"name"`
+}
+
+func (p githubTagsProvider) Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error) {
+	versions, err := p.Versions(ctx, c, source)
+	if err != nil {
+		return "", err
+	}
+	// Prefer stable tags; pre-release tags only count when enabled or there is nothing else
+	var stable []string
+	for _, v := range versions {
+		if !strings.Contains(v, "-") {
+			stable = append(stable, v)
+		}
+	}
+	if len(stable) > 0 && !source.Prerelease {
+		versions = stable
+	}
+
+	latest := newestVersion(versions)
+	if latest == "" {
+		return "", fmt.Errorf("no version tags found in %s/%s", source.Owner, source.Repo)
+	}
+	return latest, nil
+}
+
+func (githubTagsProvider) Versions(ctx context.Context, c *VersionChecker, source config.VersionSource) ([]string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/tags?per_page=100", c.githubAPIBase(source), source.Owner, source.Repo)
+
+	req, err := c.newGitHubRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []GitHubTag
+	if err := c.fetchJSON(req, "GitHub tags", &tags); err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if name := strings.TrimPrefix(tag.Name, "v"); looksLikeVersion(name) {
+			versions = append(versions, name)
+		}
+	}
+	return versions, nil
+}
+
+// cratesProvider reads versions of Rust crates from crates.io
+type cratesProvider struct{}
+
+// CratesInfo represents the crates.io API response
+type CratesInfo struct {
+	Crate struct {
+		MaxStableVersion string `json:"max_stable_version"`
+		MaxVersion       string `json:"max_version"`
+	} `json:"crate"`
+	Versions []struct {
+		Num    string `json:"num"`
+		Yanked bool   `json:"yanked"`
+	} `json:"versions"`
+}
+
+func (p cratesProvider) Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error) {
+	info, err := p.fetch(ctx, c, source)
+	if err != nil {
+		return "", err
+	}
+	if info.Crate.MaxStableVersion != "" && !source.Prerelease {
+		return info.Crate.MaxStableVersion, nil
+	}
+	return info.Crate.MaxVersion, nil
+}
+
+func (p cratesProvider) Versions(ctx context.Context, c *VersionChecker, source config.VersionSource) ([]string, error) {
+	info, err := p.fetch(ctx, c, source)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, 0, len(info.Versions))
+	for _, v := range info.Versions {
+		if !v.Yanked {
+			versions = append(versions, v.Num)
+		}
+	}
+	return versions, nil
+}
+
+func (cratesProvider) fetch(ctx context.Context, c *VersionChecker, source config.VersionSource) (*CratesInfo, error) {
+	base := c.endpoint(source, "crates", defaultCratesAPI)
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/crates/%s", base, source.Package), nil)
+	if err != nil {
+		return nil, err
+	}
+	// crates.io rejects requests without a user agent
+	req.Header.Set("User-Agent", "agenthelper (https://github.com/jschneider/agenthelper)")
+
+	var info CratesInfo
+	if err := c.fetchJSON(req, "crates.io", &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// homebrewProvider reads formula and cask versions from the formulae.brew.sh API
+type homebrewProvider struct{}
+
+// HomebrewInfo represents the formulae.brew.sh API response of a formula or cask
+type HomebrewInfo struct {
+	Version  string `json:"version"` // casks
+	Versions struct {
+		Stable string `json:"stable"`
+	} `json:"versions"` // formulae
+}
+
+func (homebrewProvider) Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error) {
+	kind := "formula"
+	if source.Cask {
+		kind = "cask"
+	}
+	base := c.endpoint(source, "homebrew", defaultHomebrewAPI)
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/%s/%s.json", base, kind, source.Package), nil)
+	if err != nil {
+		return "", err
+	}
+
+	var info HomebrewInfo
+	if err := c.fetchJSON(req, "Homebrew", &info); err != nil {
+		return "", err
+	}
+
+	version := info.Versions.Stable
+	if source.Cask {
+		// Cask versions may carry a build suffix, e.g. "1.2.3,4567"
+		version = strings.SplitN(info.Version, ",", 2)[0]
+	}
+	if version == "" {
+		return "", fmt.Errorf("no version found for %s %s", kind, source.Package)
+	}
+	return version, nil
+}
+
+// httpJSONProvider reads a version from any JSON document using a simple JSONPath
+type httpJSONProvider struct{}
+
+func (httpJSONProvider) Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error) {
+	if source.URL == "" || source.JSONPath == "" {
+		return "", fmt.Errorf("http-json version source requires url and json_path")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", source.URL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var doc interface{}
+	if err := c.fetchJSON(req, hostOf(source.URL), &doc); err != nil {
+		return "", err
+	}
+
+	value, err := evalJSONPath(doc, source.JSONPath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(fmt.Sprint(value), "v"), nil
+}
+
+// httpRegexProvider extracts a version from any text document with a regular expression
+type httpRegexProvider struct{}
+
+func (httpRegexProvider) Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error) {
+	if source.URL == "" || source.Pattern == "" {
+		return "", fmt.Errorf("http-regex version source requires url and pattern")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", source.URL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := c.fetchBody(req, hostOf(source.URL))
+	if err != nil {
+		return "", err
+	}
+
+	version := ExtractVersion(string(body), source.Pattern)
+	if version == "" {
+		return "", fmt.Errorf("pattern %q did not match %s", source.Pattern, source.URL)
+	}
+	return strings.TrimPrefix(version, "v"), nil
+}
+
+// commandProvider runs a shell command, e.g. a package manager query, and extracts the
+// version from its output
+type commandProvider struct{}
+
+func (commandProvider) Latest(ctx context.Context, c *VersionChecker, source config.VersionSource) (string, error) {
+	if source.Command == "" {
+		return "", fmt.Errorf("command version source requires command")
+	}
+	// The command may query the network, and its output is not cached
+	if c.offline() {
+		return "", ErrOffline
+	}
+
+	var output bytes.Buffer
+	err := c.Runner.Run(ctx, &platform.Command{Line: source.Command, Stdout: &output, Stderr: &output})
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if err != nil {
+		return "", fmt.Errorf("version command failed: %w\n%s", err, output.String())
+	}
+
+	version := ExtractVersion(output.String(), source.Pattern)
+	if version == "" {
+		return "", fmt.Errorf("could not find a version in the output of %q", source.Command)
+	}
+	return strings.TrimPrefix(version, "v"), nil
+}
+
+// fetchBody performs a cached GET and fails on any status other than 200
+func (c *VersionChecker) fetchBody(req *http.Request, name string) ([]byte, error) {
+	body, status, err := c.cachedGet(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s version: %w", name, err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", name, status)
+	}
+	return body, nil
+}
+
+// fetchJSON performs a cached GET and decodes the JSON response into v
+func (c *VersionChecker) fetchJSON(req *http.Request, name string, v interface{}) error {
+	body, err := c.fetchBody(req, name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", name, err)
+	}
+	return nil
+}
+
+func hostOf(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return rawURL
+}
+
+var jsonPathSegment = regexp.MustCompile(`\.([^.\[]+)|\[(-?\d+)\]|\['([^']+)'\]`)
+
+// evalJSONPath evaluates a JSONPath subset: $.field.nested[0]['key.with.dots'] where
+// negative indices count from the end
+func evalJSONPath(doc interface{}, path string) (interface{}, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if rest != "" && !strings.HasPrefix(rest, ".") && !strings.HasPrefix(rest, "[") {
+		rest = "." + rest
+	}
+
+	current := doc
+	for rest != "" {
+		loc := jsonPathSegment.FindStringSubmatchIndex(rest)
+		if loc == nil || loc[0] != 0 {
+			return nil, fmt.Errorf("unsupported json_path %q", path)
+		}
+		match := jsonPathSegment.FindStringSubmatch(rest)
+		rest = rest[loc[1]:]
+
+		switch {
+		case match[2] != "":
+			items, ok := current.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("json_path %q: not an array", path)
+			}
+			index, _ := strconv.Atoi(match[2])
+			if index < 0 {
+				index += len(items)
+			}
+			if index < 0 || index >= len(items) {
+				return nil, fmt.Errorf("json_path %q: index out of range", path)
+			}
+			current = items[index]
+		default:
+			key := match[1] + match[3]
+			fields, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("json_path %q: not an object", path)
+			}
+			value, ok := fields[key]
+			if !ok {
+				return nil, fmt.Errorf("json_path %q: field %q not found", path, key)
+			}
+			current = value
+		}
+	}
+
+	switch current.(type) {
+	case map[string]interface{}, []interface{}, nil:
+		return nil, fmt.Errorf("json_path %q does not point at a version", path)
+	}
+	return current, nil
+}
+
+var versionLike = regexp.MustCompile(`^\d+(\.\d+)*`)
+
+func looksLikeVersion(s string) bool {
+	return versionLike.MatchString(s)
+}
+
+// newestVersion returns the highest of a list of versions, see compareVersions
+func newestVersion(versions []string) string {
+	if len(versions) == 0 {
+		return ""
+	}
+	sorted := append([]string(nil), versions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareVersions(sorted[i], sorted[j]) < 0
+	})
+	return sorted[len(sorted)-1]
+}
+
+// compareVersions orders two versions by semver precedence, so 1.2.0-rc.1 comes before
+// 1.2.0. Versions without a semver form, such as those with four or more components, are
+// compared by their numeric components, with a suffixed version before its bare core.
+func compareVersions(a, b string) int {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA == nil && errB == nil {
+		return va.Compare(vb)
+	}
+
+	coreA, suffixA := splitVersionCore(a)
+	coreB, suffixB := splitVersionCore(b)
+	if c := compareNumeric(coreA, coreB); c != 0 {
+		return c
+	}
+	switch {
+	case suffixA == suffixB:
+		return 0
+	case suffixA == "":
+		return 1
+	case suffixB == "":
+		return -1
+	}
+	if c := compareNumeric(suffixA, suffixB); c != 0 {
+		return c
+	}
+	return strings.Compare(suffixA, suffixB)
+}
+
+var versionCore = regexp.MustCompile(`^[vV]?(\d+(?:\.\d+)*)(.*)$`)
+
+// splitVersionCore splits a version into its dotted numeric core and the rest, e.g.
+// 1.2.3.4-beta into 1.2.3.4 and -beta
+func splitVersionCore(version string) (string, string) {
+	m := versionCore.FindStringSubmatch(version)
+	if m == nil {
+		return "", version
+	}
+	return m[1], m[2]
+}
+
+var numericPart = regexp.MustCompile(`\d+`)
+
+// compareNumeric compares the numeric components of two versions in order
+func compareNumeric(a, b string) int {
+	pa := numericPart.FindAllString(a, -1)
+	pb := numericPart.FindAllString(b, -1)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, _ := strconv.Atoi(pa[i])
+		nb, _ := strconv.Atoi(pb[i])
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(pa) - len(pb)
+}
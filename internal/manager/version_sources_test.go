@@ -0,0 +1,621 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/jschneider/agenthelper/internal/config"
+	"github.com/jschneider/agenthelper/internal/platform"
+	"github.com/jschneider/agenthelper/internal/platform/platformtest"
+)
+
+const npmAbbreviated = "application/vnd.npm.install-v1+json"
+
+// fixtures maps request paths, optionally with "|<Accept header>", to the recorded
+// responses in testdata/versions. {{server}} in a response is replaced with the server URL.
+var fixtures = map[string]string{
+	"/npm/@anthropic-ai%2fclaude-code":                            "npm-package.json",
+	"/npm/@anthropic-ai%2fclaude-code|" + npmAbbreviated:          "npm-package-abbreviated.json",
+	"/repos/acme/tool/releases/latest":                            "github-release-latest.json",
+	"/repos/acme/tool/releases":                                   "github-releases.json",
+	"/repos/acme/tool/tags":                                       "github-tags.json",
+	"/repos/microsoft/winget-pkgs/contents/manifests/w/Warp/Warp": "winget-pkgs-contents.json",
+	"/pypi/aider-chat/json":                                       "pypi-package.json",
+	"/api/v1/crates/ripgrep":                                      "crates-crate.json",
+	"/homebrew/formula/gh.json":                                   "homebrew-formula.json",
+	"/homebrew/cask/warp.json":                                    "homebrew-cask.json",
+	"/api/update/win32-x64-user/stable/latest":                    "vscode-stable.json",
+	"/api/update/win32-x64-user/insider/latest":                   "vscode-insider.json",
+	"/releases.json":                                              "releases.json",
+	"/latest.yml":                                                 "todesktop-latest.yml",
+	"/downloads/tool_2.3.0_linux_amd64.deb":                       "release-asset.deb",
+	"/downloads/tool_2.3.0_linux_arm64.deb":                       "release-asset.deb",
+	"/latest/linux-deb-x64/insider":                               "release-asset.deb",
+	"/api/update/linux-deb-x64/insider/latest":                    "vscode-insider-deb.json",
+	"/downloads/install-2.3.0.sh":                                 "install.sh",
+	"/downloads/install-2.3.0.sh.minisig":                         "install.sh.minisig",
+	"/downloads/SHA256SUMS":                                       "SHA256SUMS",
+}
+
+// failure is a canned error response
+type failure struct {
+	status int
+	header map[string]string
+	body   string
+}
+
+// fixtureServer serves the recorded responses of every version source, or a failure for
+// all requests while one is set
+type fixtureServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	failure  *failure
+	requests []*http.Request
+}
+
+func newFixtureServer(t *testing.T) *fixtureServer {
+	t.Helper()
+	s := &fixtureServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fixtureServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	f := s.failure
+	s.mu.Unlock()
+
+	if f != nil {
+		for key, value := range f.header {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(f.status)
+		fmt.Fprint(w, f.body)
+		return
+	}
+
+	name, ok := fixtures[r.URL.EscapedPath()+"|"+r.Header.Get("Accept")]
+	if !ok {
+		name, ok = fixtures[r.URL.EscapedPath()]
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "versions", name))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(bytes.ReplaceAll(data, []byte("{{server}}"), []byte(s.URL)))
+}
+
+// fail answers all further requests with f, or with the fixtures again for nil
+func (s *fixtureServer) fail(f *failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failure = f
+}
+
+// lastRequest returns the most recent request the server received
+func (s *fixtureServer) lastRequest(t *testing.T) *http.Request {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		t.Fatal("no request reached the server")
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+// newTestChecker returns a checker with every endpoint pointed at the server. The version
+// cache goes to a temporary home directory.
+func newTestChecker(t *testing.T, server *fixtureServer) (*VersionChecker, *platformtest.Runner) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("GITHUB_TOKEN", "test-token")
+
+	_, runner := platformtest.NewSystem(platform.Linux)
+	return &VersionChecker{
+		Client: server.Client(),
+		BaseURLs: map[string]string{
+			"npm":           server.URL + "/npm/",
+			"pypi":          server.URL + "/simple",
+			"github":        server.URL,
+			"crates":        server.URL,
+			"homebrew":      server.URL + "/homebrew",
+			"vscode-update": server.URL,
+		},
+		Runner:   runner,
+		CacheTTL: DefaultCacheTTL,
+	}, runner
+}
+
+// sourceTool returns a tool that looks up its versions from source
+func sourceTool(source config.VersionSource) *config.ToolDefinition {
+	return &config.ToolDefinition{Key: "tool", Name: "tool", VersionSource: source}
+}
+
+func TestVersionProviders(t *testing.T) {
+	tests := []struct {
+		name         string
+		source       config.VersionSource // URLs are relative to the fixture server
+		wantLatest   string
+		wantVersions []string // nil for sources that only know the latest version
+	}{
+		{
+			name:         "npm",
+			source:       config.VersionSource{Type: "npm", Package: "@anthropic-ai/claude-code"},
+			wantLatest:   "1.0.35",
+			wantVersions: []string{"1.0.33", "1.0.34", "1.0.35", "1.1.0-beta.2"},
+		},
+		{
+			name:         "npm dist-tag",
+			source:       config.VersionSource{Type: "npm", Package: "@anthropic-ai/claude-code", Channel: "next"},
+			wantLatest:   "1.1.0-beta.2",
+			wantVersions: []string{"1.0.33", "1.0.34", "1.0.35", "1.1.0-beta.2"},
+		},
+		{
+			name:         "github releases",
+			source:       config.VersionSource{Type: "github", Owner: "acme", Repo: "tool"},
+			wantLatest:   "2.3.0",
+			wantVersions: []string{"2.2.0", "2.2.1", "2.3.0", "2.4.0-rc.1"},
+		},
+		{
+			name:         "github releases with prereleases",
+			source:       config.VersionSource{Type: "github", Owner: "acme", Repo: "tool", Prerelease: true},
+			wantLatest:   "2.4.0-rc.1",
+			wantVersions: []string{"2.2.0", "2.2.1", "2.3.0", "2.4.0-rc.1"},
+		},
+		{
+			name:         "github tags",
+			source:       config.VersionSource{Type: "github-tags", Owner: "acme", Repo: "tool"},
+			wantLatest:   "1.10.0",
+			wantVersions: []string{"1.10.0", "1.11.0-beta.1", "1.8.2", "1.9.0"},
+		},
+		{
+			name:         "github tags with prereleases",
+			source:       config.VersionSource{Type: "github-tags", Owner: "acme", Repo: "tool", Prerelease: true},
+			wantLatest:   "1.11.0-beta.1",
+			wantVersions: []string{"1.10.0", "1.11.0-beta.1", "1.8.2", "1.9.0"},
+		},
+		{
+			name:       "winget-pkgs",
+			source:     config.VersionSource{Type: "winget-pkgs", Package: "w/Warp/Warp"},
+			wantLatest: "v0.2024.11.12.08.02.stable_00",
+		},
+		{
+			name:         "pypi",
+			source:       config.VersionSource{Type: "pypi", Package: "aider-chat"},
+			wantLatest:   "0.50.1",
+			wantVersions: []string{"0.49.0", "0.50.0", "0.50.1", "0.51.0rc1"},
+		},
+		{
+			name:         "pypi with prereleases",
+			source:       config.VersionSource{Type: "pypi", Package: "aider-chat", Prerelease: true},
+			wantLatest:   "0.51.0rc1",
+			wantVersions: []string{"0.49.0", "0.50.0", "0.50.1", "0.51.0rc1"},
+		},
+		{
+			name:         "crates",
+			source:       config.VersionSource{Type: "crates", Package: "ripgrep"},
+			wantLatest:   "14.1.1",
+			wantVersions: []string{"14.1.0", "14.1.1", "15.0.0-rc.1"},
+		},
+		{
+			name:         "crates with prereleases",
+			source:       config.VersionSource{Type: "crates", Package: "ripgrep", Prerelease: true},
+			wantLatest:   "15.0.0-rc.1",
+			wantVersions: []string{"14.1.0", "14.1.1", "15.0.0-rc.1"},
+		},
+		{
+			name:       "homebrew formula",
+			source:     config.VersionSource{Type: "homebrew", Package: "gh"},
+			wantLatest: "2.63.2",
+		},
+		{
+			name:       "homebrew cask",
+			source:     config.VersionSource{Type: "homebrew", Package: "warp", Cask: true},
+			wantLatest: "0.2024.11.12.08.02.stable_00",
+		},
+		{
+			name:       "vscode-update",
+			source:     config.VersionSource{Type: "vscode-update"},
+			wantLatest: "1.95.3",
+		},
+		{
+			name:       "vscode-update insider",
+			source:     config.VersionSource{Type: "vscode-update", Channel: "insider"},
+			wantLatest: "1.96.0",
+		},
+		{
+			name:       "http-json",
+			source:     config.VersionSource{Type: "http-json", URL: "/releases.json", JSONPath: "$.releases[0].version"},
+			wantLatest: "3.2.1",
+		},
+		{
+			name:       "http-regex",
+			source:     config.VersionSource{Type: "http-regex", URL: "/latest.yml", Pattern: `version:\s*(\d+\.\d+\.\d+)`},
+			wantLatest: "0.45.11",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFixtureServer(t)
+			checker, _ := newTestChecker(t, server)
+			if tt.source.URL != "" {
+				tt.source.URL = server.URL + tt.source.URL
+			}
+			tool := sourceTool(tt.source)
+
+			latest, err := checker.LatestVersion(context.Background(), tool)
+			if err != nil || latest != tt.wantLatest {
+				t.Errorf("LatestVersion() = %q, %v; want %q", latest, err, tt.wantLatest)
+			}
+
+			if tt.wantVersions == nil {
+				return
+			}
+			versions, err := checker.AvailableVersions(context.Background(), tool)
+			sort.Strings(versions)
+			if err != nil || strings.Join(versions, " ") != strings.Join(tt.wantVersions, " ") {
+				t.Errorf("AvailableVersions() = %q, %v; want %q", versions, err, tt.wantVersions)
+			}
+		})
+	}
+}
+
+func TestVersionProviderFailures(t *testing.T) {
+	sources := []config.VersionSource{
+		{Type: "npm", Package: "@anthropic-ai/claude-code"},
+		{Type: "github", Owner: "acme", Repo: "tool"},
+		{Type: "github-tags", Owner: "acme", Repo: "tool"},
+		{Type: "winget-pkgs", Package: "w/Warp/Warp"},
+		{Type: "pypi", Package: "aider-chat"},
+		{Type: "crates", Package: "ripgrep"},
+		{Type: "homebrew", Package: "gh"},
+		{Type: "vscode-update"},
+		{Type: "http-json", URL: "/releases.json", JSONPath: "$.releases[0].version"},
+		{Type: "http-regex", URL: "/latest.yml", Pattern: `version:\s*(\d+\.\d+\.\d+)`},
+	}
+
+	failures := []struct {
+		name    string
+		failure failure
+		wantErr string // %s is replaced with the type of the source
+	}{
+		{"not found", failure{status: http.StatusNotFound, body: `{"error":"Not found"}`}, "status 404"},
+		{"server error", failure{status: http.StatusBadGateway, body: "<html>502 Bad Gateway</html>"}, "status 502"},
+		{"rate limited", failure{
+			status: http.StatusTooManyRequests,
+			header: map[string]string{"Retry-After": "60"},
+			body:   `{"error":"Too many requests"}`,
+		}, "status 429"},
+		{"github rate limit", failure{
+			status: http.StatusForbidden,
+			header: map[string]string{"X-RateLimit-Limit": "60", "X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1735689600"},
+			body:   `{"message":"API rate limit exceeded","documentation_url":"https://docs.github.com/rest/overview/resources-in-the-rest-api#rate-limiting"}`,
+		}, "status 403"},
+		{"malformed json", failure{status: http.StatusOK, body: `{"dist-tags": {"latest": "1.0`}, "failed to parse"},
+	}
+
+	for _, source := range sources {
+		for _, f := range failures {
+			source, f := source, f
+			t.Run(source.Type+" "+f.name, func(t *testing.T) {
+				server := newFixtureServer(t)
+				checker, _ := newTestChecker(t, server)
+				if source.URL != "" {
+					source.URL = server.URL + source.URL
+				}
+				server.fail(&f.failure)
+
+				wantErr := f.wantErr
+				if source.Type == "http-regex" && f.name == "malformed json" {
+					// Text sources don't parse JSON; the pattern finds no version instead
+					wantErr = "did not match"
+				}
+
+				latest, err := checker.LatestVersion(context.Background(), sourceTool(source))
+				if err == nil || !strings.Contains(err.Error(), wantErr) {
+					t.Errorf("LatestVersion() = %q, %v; want error containing %q", latest, err, wantErr)
+				}
+			})
+		}
+	}
+}
+
+func TestVersionCacheFallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		failure failure
+		wantErr bool
+	}{
+		{"rate limited", failure{status: http.StatusTooManyRequests}, false},
+		{"github rate limit", failure{status: http.StatusForbidden, header: map[string]string{"X-RateLimit-Remaining": "0"}}, false},
+		{"server error", failure{status: http.StatusServiceUnavailable}, false},
+		{"not found", failure{status: http.StatusNotFound}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFixtureServer(t)
+			checker, _ := newTestChecker(t, server)
+			checker.CacheTTL = 0 // always revalidate
+			tool := sourceTool(config.VersionSource{Type: "github", Owner: "acme", Repo: "tool"})
+
+			if _, err := checker.LatestVersion(context.Background(), tool); err != nil {
+				t.Fatalf("LatestVersion() failed: %v", err)
+			}
+
+			server.fail(&tt.failure)
+			latest, err := checker.LatestVersion(context.Background(), tool)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("LatestVersion() = %q, want an error", latest)
+				}
+				return
+			}
+			if err != nil || latest != "2.3.0" {
+				t.Errorf("LatestVersion() = %q, %v; want the cached 2.3.0", latest, err)
+			}
+		})
+	}
+}
+
+func TestVersionRequests(t *testing.T) {
+	server := newFixtureServer(t)
+	checker, _ := newTestChecker(t, server)
+	ctx := context.Background()
+
+	// GITHUB_TOKEN is only for github.com, not for any server a registry points at
+	github := sourceTool(config.VersionSource{Type: "github", Owner: "acme", Repo: "tool"})
+	checker.LatestVersion(ctx, github)
+	if got := server.lastRequest(t).Header.Get("Authorization"); got != "" {
+		t.Errorf("GitHub request Authorization = %q, want no token for %s", got, server.URL)
+	}
+	t.Setenv("GH_HOST", server.Listener.Addr().(*net.TCPAddr).IP.String())
+	t.Setenv("GH_ENTERPRISE_TOKEN", "enterprise-token")
+	checker.Refresh = true
+	checker.LatestVersion(ctx, github)
+	if got := server.lastRequest(t).Header.Get("Authorization"); got != "Bearer enterprise-token" {
+		t.Errorf("GitHub request Authorization = %q, want the token of GH_ENTERPRISE_TOKEN", got)
+	}
+
+	checker.LatestVersion(ctx, sourceTool(config.VersionSource{Type: "crates", Package: "ripgrep"}))
+	if got := server.lastRequest(t).Header.Get("User-Agent"); !strings.HasPrefix(got, "agenthelper") {
+		t.Errorf("crates.io request User-Agent = %q, want agenthelper", got)
+	}
+
+	checker.AvailableVersions(ctx, sourceTool(config.VersionSource{Type: "npm", Package: "@anthropic-ai/claude-code"}))
+	if got := server.lastRequest(t).Header.Get("Accept"); got != npmAbbreviated {
+		t.Errorf("npm version list Accept = %q, want %q", got, npmAbbreviated)
+	}
+}
+
+func TestGitHubCLIToken(t *testing.T) {
+	server := newFixtureServer(t)
+	checker, runner := newTestChecker(t, server)
+	checker.Refresh = true
+
+	host := server.Listener.Addr().(*net.TCPAddr).IP.String()
+	runner.On("gh auth token --hostname "+host, platformtest.Response{Stdout: "cli-token\n"})
+	github := sourceTool(config.VersionSource{Type: "github", Owner: "acme", Repo: "tool"})
+	for i := 0; i < 2; i++ {
+		checker.LatestVersion(context.Background(), github)
+		if got := server.lastRequest(t).Header.Get("Authorization"); got != "Bearer cli-token" {
+			t.Errorf("GitHub request Authorization = %q, want the token of gh", got)
+		}
+	}
+	if calls := runner.CallsMatching("gh auth token"); len(calls) != 1 {
+		t.Errorf("gh ran %d times, want once per host", len(calls))
+	}
+}
+
+func TestVersionSourceEndpoints(t *testing.T) {
+	server := newFixtureServer(t)
+	mirror := newFixtureServer(t)
+	checker, _ := newTestChecker(t, server)
+	ctx := context.Background()
+
+	// The package manager configuration is ignored when an endpoint is configured
+	t.Setenv("npm_config_registry", "http://127.0.0.1:1/unreachable/")
+	t.Setenv("PIP_INDEX_URL", "http://127.0.0.1:1/unreachable/simple")
+	if _, err := checker.LatestVersion(ctx, sourceTool(config.VersionSource{Type: "npm", Package: "@anthropic-ai/claude-code"})); err != nil {
+		t.Errorf("npm lookup with a configured endpoint failed: %v", err)
+	}
+	if _, err := checker.LatestVersion(ctx, sourceTool(config.VersionSource{Type: "pypi", Package: "aider-chat"})); err != nil {
+		t.Errorf("pypi lookup with a configured endpoint failed: %v", err)
+	}
+
+	// A tool's registry takes precedence over the configured endpoints
+	sources := []config.VersionSource{
+		{Type: "npm", Package: "@anthropic-ai/claude-code", Registry: mirror.URL + "/npm"},
+		{Type: "pypi", Package: "aider-chat", Registry: mirror.URL + "/simple"},
+		{Type: "github", Owner: "acme", Repo: "tool", Registry: mirror.URL},
+		{Type: "crates", Package: "ripgrep", Registry: mirror.URL + "/"},
+		{Type: "homebrew", Package: "gh", Registry: mirror.URL + "/homebrew"},
+		{Type: "vscode-update", Registry: mirror.URL},
+	}
+	for _, source := range sources {
+		if _, err := checker.LatestVersion(ctx, sourceTool(source)); err != nil {
+			t.Errorf("%s lookup with a registry failed: %v", source.Type, err)
+		}
+	}
+	mirror.mu.Lock()
+	defer mirror.mu.Unlock()
+	if len(mirror.requests) != len(sources) {
+		t.Errorf("registry received %d requests, want %d", len(mirror.requests), len(sources))
+	}
+}
+
+func TestVersionSourceURLs(t *testing.T) {
+	urls, err := VersionSourceURLs(map[string]string{"npm": "https://npm.example.com/", "gitlab": "https://gitlab.example.com"})
+	if err == nil || !strings.Contains(err.Error(), "gitlab") {
+		t.Errorf("VersionSourceURLs() error = %v, want the unknown gitlab", err)
+	}
+
+	checker := NewVersionChecker(http.DefaultClient, nil, Options{VersionSources: urls})
+	if got := checker.npmRegistry(config.VersionSource{Package: "tool"}); got != "https://npm.example.com/" {
+		t.Errorf("npmRegistry() = %q, want the configured endpoint", got)
+	}
+	if got := checker.endpoint(config.VersionSource{}, "crates", defaultCratesAPI); got != defaultCratesAPI {
+		t.Errorf("endpoint() = %q, want the default %q", got, defaultCratesAPI)
+	}
+}
+
+func TestCommandVersionSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		response platformtest.Response
+		want     string
+		wantErr  string
+	}{
+		{"version in output", platformtest.Response{Stdout: "tool v3.2.1 (stable)\n"}, "3.2.1", ""},
+		{"command fails", platformtest.Response{Stderr: "not found\n", ExitCode: 1}, "", "version command failed"},
+		{"no version in output", platformtest.Response{Stdout: "up to date\n"}, "", "could not find a version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, runner := newTestChecker(t, newFixtureServer(t))
+			runner.On("tool-latest", tt.response)
+
+			got, err := checker.LatestVersion(context.Background(), sourceTool(config.VersionSource{Type: "command", Command: "tool-latest"}))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LatestVersion() = %q, %v; want error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("LatestVersion() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubReleasePages(t *testing.T) {
+	// 250 releases, newest first: v250 to v1
+	var pages []string
+	server := &fixtureServer{Server: httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages = append(pages, r.URL.Query().Get("page"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var releases []string
+		for n := 250 - (page-1)*gitHubPageSize; n > 0 && n > 250-page*gitHubPageSize; n-- {
+			releases = append(releases, fmt.Sprintf(`{"tag_name": "v%d.0.0", "assets": [{"name": "tool-%d.deb"}]}`, n, n))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(releases, ","))
+	}))}
+	t.Cleanup(server.Close)
+	checker, _ := newTestChecker(t, server)
+	ctx := context.Background()
+
+	versions, err := checker.gitHubVersions(ctx, server.URL, "acme", "tool")
+	if err != nil || len(versions) != 250 || versions[249] != "1.0.0" {
+		t.Errorf("gitHubVersions() = %d versions, %v; want 250 down to 1.0.0", len(versions), err)
+	}
+	if want := []string{"1", "2", "3"}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("fetched pages %q, want %q", pages, want)
+	}
+
+	// A fresh cache, and the lookup stops at the page with the release
+	pages = nil
+	checker, _ = newTestChecker(t, server)
+	assets, err := checker.gitHubReleaseAssets(ctx, server.URL, "acme", "tool", "180.0.0")
+	if err != nil || len(assets) != 1 || assets[0].Name != "tool-180.deb" {
+		t.Errorf("gitHubReleaseAssets() = %v, %v; want tool-180.deb", assets, err)
+	}
+	if want := []string{"1"}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("fetched pages %q, want %q", pages, want)
+	}
+}
+
+func TestNewestVersion(t *testing.T) {
+	tests := []struct {
+		versions []string
+		want     string
+	}{
+		{[]string{"1.2.0", "1.2.0-rc.1"}, "1.2.0"},
+		{[]string{"1.2.0-rc.1", "1.2.0"}, "1.2.0"},
+		{[]string{"1.2.0-rc.1", "1.2.0-rc.2", "1.1.9"}, "1.2.0-rc.2"},
+		{[]string{"v1.10.0", "v1.9.0", "v1.10.0-beta.1"}, "v1.10.0"},
+		{[]string{"1.2.3.4", "1.2.3.10", "1.2.3"}, "1.2.3.10"},
+		{[]string{"1.2.3.4", "1.2.3.4-beta"}, "1.2.3.4"},
+		{[]string{"1.2.3.4-beta", "1.2.3.3"}, "1.2.3.4-beta"},
+		{[]string{"1.2.3", "1.2.3.1"}, "1.2.3.1"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := newestVersion(tt.versions); got != tt.want {
+			t.Errorf("newestVersion(%q) = %q, want %q", tt.versions, got, tt.want)
+		}
+	}
+}
+
+func TestPEP440Order(t *testing.T) {
+	// Ascending by PEP 440: dev releases, alpha, beta and release candidates, then the release
+	ordered := []string{"1.2.0.dev3", "1.2.0a1", "1.2.0b1", "1.2.0rc1", "1.2.0", "1.2.1.dev0"}
+	for i := 1; i < len(ordered); i++ {
+		lower, err := semver.NewVersion(pep440ToSemver(ordered[i-1]))
+		if err != nil {
+			t.Fatalf("pep440ToSemver(%s) = %q: %v", ordered[i-1], pep440ToSemver(ordered[i-1]), err)
+		}
+		higher, err := semver.NewVersion(pep440ToSemver(ordered[i]))
+		if err != nil {
+			t.Fatalf("pep440ToSemver(%s) = %q: %v", ordered[i], pep440ToSemver(ordered[i]), err)
+		}
+		if !lower.LessThan(higher) {
+			t.Errorf("%s (%s) does not sort before %s (%s)", ordered[i-1], lower, ordered[i], higher)
+		}
+	}
+}
+
+func TestNpmAuthToken(t *testing.T) {
+	npmrc := filepath.Join(t.TempDir(), ".npmrc")
+	content := "//npm.company.com/:_authToken=company\n" +
+		"//npm.company.com/api/private/:_authToken=private\n" +
+		"//registry.example.com:_authToken=example\n"
+	if err := os.WriteFile(npmrc, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NPM_CONFIG_USERCONFIG", npmrc)
+
+	tests := []struct {
+		registry string
+		want     string
+	}{
+		{"https://npm.company.com/", "company"},
+		{"https://npm.company.com", "company"},
+		{"https://npm.company.com/api/private/", "private"},
+		{"https://npm.company.com/api/privateer/", "company"},
+		{"https://registry.example.com/npm/", "example"},
+		{"https://npm.company.com.evil.io/", ""},
+		{"https://registry.example.com.evil.io/", ""},
+		{"https://registry.example.com:8443/", ""},
+	}
+	for _, tt := range tests {
+		if got := npmAuthToken(tt.registry); got != tt.want {
+			t.Errorf("npmAuthToken(%s) = %q, want %q", tt.registry, got, tt.want)
+		}
+	}
+}
@@ -2,9 +2,11 @@ package platform
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 )
 
 // PackageManager defines the interface for package managers
@@ -20,6 +22,17 @@ type PackageManager interface {
 type BasePackageManager struct {
 	name    string
 	command string
+	sys     *System
+}
+
+// run runs a command of the package manager
+func (b *BasePackageManager) run(command string) error {
+	var stderr bytes.Buffer
+	err := b.sys.Runner.Run(context.Background(), &Command{Line: command, Stderr: &stderr})
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, stderr.String())
+	}
+	return nil
 }
 
 // runCommand executes a command and returns output
@@ -71,10 +84,18 @@ func NewShellCommand(command string) *exec.Cmd {
 	return cmd
 }
 
-// commandExists checks if a command is available
-func commandExists(name string) bool {
-	_, err := exec.LookPath(name)
-	return err == nil
+// NewShellCommandContext creates a shell command that is killed when the context is done
+func NewShellCommandContext(ctx context.Context, command string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if IsWindows() {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+		hideWindow(cmd)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	// Don't wait forever for output pipes held open by orphaned child processes
+	cmd.WaitDelay = 2 * time.Second
+	return cmd
 }
 
 // WinGet implements PackageManager for Windows Package Manager
@@ -83,30 +104,31 @@ type WinGet struct {
 }
 
 func NewWinGet() *WinGet {
+	return LocalSystem().newWinGet()
+}
+
+func (s *System) newWinGet() *WinGet {
 	return &WinGet{
-		BasePackageManager{name: "WinGet", command: "winget"},
+		BasePackageManager{name: "WinGet", command: "winget", sys: s},
 	}
 }
 
 func (w *WinGet) Name() string { return w.name }
 
 func (w *WinGet) IsAvailable() bool {
-	return IsWindows() && commandExists("winget")
+	return w.sys.OS == Windows && w.sys.HasCommand("winget")
 }
 
 func (w *WinGet) Install(command string) error {
-	_, err := runCommand(command)
-	return err
+	return w.run(command)
 }
 
 func (w *WinGet) Update(command string) error {
-	_, err := runCommand(command)
-	return err
+	return w.run(command)
 }
 
 func (w *WinGet) Uninstall(command string) error {
-	_, err := runCommand(command)
-	return err
+	return w.run(command)
 }
 
 // Homebrew implements PackageManager for macOS/Linux Homebrew
@@ -115,30 +137,31 @@ type Homebrew struct {
 }
 
 func NewHomebrew() *Homebrew {
+	return LocalSystem().newHomebrew()
+}
+
+func (s *System) newHomebrew() *Homebrew {
 	return &Homebrew{
-		BasePackageManager{name: "Homebrew", command: "brew"},
+		BasePackageManager{name: "Homebrew", command: "brew", sys: s},
 	}
 }
 
 func (h *Homebrew) Name() string { return h.name }
 
 func (h *Homebrew) IsAvailable() bool {
-	return (IsDarwin() || IsLinux()) && commandExists("brew")
+	return (h.sys.OS == Darwin || h.sys.OS == Linux) && h.sys.HasCommand("brew")
 }
 
 func (h *Homebrew) Install(command string) error {
-	_, err := runCommand(command)
-	return err
+	return h.run(command)
 }
 
 func (h *Homebrew) Update(command string) error {
-	_, err := runCommand(command)
-	return err
+	return h.run(command)
 }
 
 func (h *Homebrew) Uninstall(command string) error {
-	_, err := runCommand(command)
-	return err
+	return h.run(command)
 }
 
 // Apt implements PackageManager for Debian/Ubuntu apt
@@ -147,30 +170,31 @@ type Apt struct {
 }
 
 func NewApt() *Apt {
+	return LocalSystem().newApt()
+}
+
+func (s *System) newApt() *Apt {
 	return &Apt{
-		BasePackageManager{name: "apt", command: "apt"},
+		BasePackageManager{name: "apt", command: "apt", sys: s},
 	}
 }
 
 func (a *Apt) Name() string { return a.name }
 
 func (a *Apt) IsAvailable() bool {
-	return IsLinux() && commandExists("apt")
+	return a.sys.OS == Linux && a.sys.HasCommand("apt")
 }
 
 func (a *Apt) Install(command string) error {
-	_, err := runCommand(command)
-	return err
+	return a.run(command)
 }
 
 func (a *Apt) Update(command string) error {
-	_, err := runCommand(command)
-	return err
+	return a.run(command)
 }
 
 func (a *Apt) Uninstall(command string) error {
-	_, err := runCommand(command)
-	return err
+	return a.run(command)
 }
 
 // Pacman implements PackageManager for Arch Linux
@@ -179,30 +203,31 @@ type Pacman struct {
 }
 
 func NewPacman() *Pacman {
+	return LocalSystem().newPacman()
+}
+
+func (s *System) newPacman() *Pacman {
 	return &Pacman{
-		BasePackageManager{name: "pacman", command: "pacman"},
+		BasePackageManager{name: "pacman", command: "pacman", sys: s},
 	}
 }
 
 func (p *Pacman) Name() string { return p.name }
 
 func (p *Pacman) IsAvailable() bool {
-	return IsLinux() && commandExists("pacman")
+	return p.sys.OS == Linux && p.sys.HasCommand("pacman")
 }
 
 func (p *Pacman) Install(command string) error {
-	_, err := runCommand(command)
-	return err
+	return p.run(command)
 }
 
 func (p *Pacman) Update(command string) error {
-	_, err := runCommand(command)
-	return err
+	return p.run(command)
 }
 
 func (p *Pacman) Uninstall(command string) error {
-	_, err := runCommand(command)
-	return err
+	return p.run(command)
 }
 
 // Npm implements PackageManager for Node.js npm
@@ -211,30 +236,31 @@ type Npm struct {
 }
 
 func NewNpm() *Npm {
+	return LocalSystem().newNpm()
+}
+
+func (s *System) newNpm() *Npm {
 	return &Npm{
-		BasePackageManager{name: "npm", command: "npm"},
+		BasePackageManager{name: "npm", command: "npm", sys: s},
 	}
 }
 
 func (n *Npm) Name() string { return n.name }
 
 func (n *Npm) IsAvailable() bool {
-	return commandExists("npm")
+	return n.sys.HasCommand("npm")
 }
 
 func (n *Npm) Install(command string) error {
-	_, err := runCommand(command)
-	return err
+	return n.run(command)
 }
 
 func (n *Npm) Update(command string) error {
-	_, err := runCommand(command)
-	return err
+	return n.run(command)
 }
 
 func (n *Npm) Uninstall(command string) error {
-	_, err := runCommand(command)
-	return err
+	return n.run(command)
 }
 
 // Pip implements PackageManager for Python pip
@@ -243,85 +269,85 @@ type Pip struct {
 }
 
 func NewPip() *Pip {
+	return LocalSystem().newPip()
+}
+
+func (s *System) newPip() *Pip {
 	return &Pip{
-		BasePackageManager{name: "pip", command: "pip"},
+		BasePackageManager{name: "pip", command: "pip", sys: s},
 	}
 }
 
 func (p *Pip) Name() string { return p.name }
 
 func (p *Pip) IsAvailable() bool {
-	return commandExists("pip") || commandExists("pip3")
+	return p.sys.HasCommand("pip") || p.sys.HasCommand("pip3")
 }
 
 func (p *Pip) Install(command string) error {
-	_, err := runCommand(command)
-	return err
+	return p.run(command)
 }
 
 func (p *Pip) Update(command string) error {
-	_, err := runCommand(command)
-	return err
+	return p.run(command)
 }
 
 func (p *Pip) Uninstall(command string) error {
-	_, err := runCommand(command)
-	return err
+	return p.run(command)
 }
 
 // DetectPackageManagers returns all available package managers for the current platform
 func DetectPackageManagers() []PackageManager {
-	var managers []PackageManager
+	return LocalSystem().DetectPackageManagers()
+}
+
+// DetectPackageManagers returns all available package managers of the system
+func (s *System) DetectPackageManagers() []PackageManager {
+	var names []string
 
 	// Platform-specific managers first
-	switch Current().OS {
+	switch s.OS {
 	case Windows:
-		if pm := NewWinGet(); pm.IsAvailable() {
-			managers = append(managers, pm)
-		}
+		names = []string{"winget"}
 	case Darwin:
-		if pm := NewHomebrew(); pm.IsAvailable() {
-			managers = append(managers, pm)
-		}
+		names = []string{"brew"}
 	case Linux:
-		if pm := NewHomebrew(); pm.IsAvailable() {
-			managers = append(managers, pm)
-		}
-		if pm := NewApt(); pm.IsAvailable() {
-			managers = append(managers, pm)
-		}
-		if pm := NewPacman(); pm.IsAvailable() {
-			managers = append(managers, pm)
-		}
+		names = []string{"brew", "apt", "pacman"}
 	}
 
 	// Cross-platform managers
-	if pm := NewNpm(); pm.IsAvailable() {
-		managers = append(managers, pm)
-	}
-	if pm := NewPip(); pm.IsAvailable() {
-		managers = append(managers, pm)
-	}
+	names = append(names, "npm", "pip")
 
+	var managers []PackageManager
+	for _, name := range names {
+		if pm := s.PackageManager(name); pm.IsAvailable() {
+			managers = append(managers, pm)
+		}
+	}
 	return managers
 }
 
 // GetPackageManagerByName returns a specific package manager by name
 func GetPackageManagerByName(name string) PackageManager {
-	name = strings.ToLower(name)
-	switch name {
+	return LocalSystem().PackageManager(name)
+}
+
+// PackageManager returns a package manager of the system by name, or nil for an unknown
+// name
+func (s *System) PackageManager(name string) PackageManager {
+	switch strings.ToLower(name) {
 	case "winget":
-		return NewWinGet()
+		return s.newWinGet()
 	case "brew", "homebrew":
-		return NewHomebrew()
+		return s.newHomebrew()
 	case "apt":
-		return NewApt()
+		return s.newApt()
 	case "pacman":
-		return NewPacman()
+		return s.newPacman()
 	case "npm":
-		return NewNpm()
+		return s.newNpm()
 	case "pip":
-		return NewPip()
+		return s.newPip()
 	default:
 		return nil
 	}
@@ -3,6 +3,7 @@ package platform
 import (
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Paths holds OS-specific paths
@@ -93,3 +94,43 @@ func GetExecutablePath(name string) (string, error) {
 
 	return "", os.ErrNotExist
 }
+
+// FindExecutables returns every match of an executable on PATH in PATH order, not just
+// the first one that runs. Entries that are the same file are listed once.
+func FindExecutables(name string) []string {
+	names := []string{name}
+	if IsWindows() && filepath.Ext(name) == "" {
+		names = nil
+		exts := os.Getenv("PATHEXT")
+		if exts == "" {
+			exts = ".COM;.EXE;.BAT;.CMD"
+		}
+		for _, ext := range strings.Split(exts, ";") {
+			if ext != "" {
+				names = append(names, name+strings.ToLower(ext))
+			}
+		}
+	}
+
+	var found []string
+	seen := make(map[string]bool)
+	for _, dir := range GetEnvPath() {
+		for _, n := range names {
+			fullPath := filepath.Join(dir, n)
+			info, err := os.Stat(fullPath)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			resolved, err := filepath.EvalSymlinks(fullPath)
+			if err != nil {
+				resolved = fullPath
+			}
+			if seen[resolved] {
+				continue
+			}
+			seen[resolved] = true
+			found = append(found, fullPath)
+		}
+	}
+	return found
+}
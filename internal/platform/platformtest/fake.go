@@ -0,0 +1,142 @@
+// Package platformtest provides a simulated system for tests: a scripted runner that
+// records the commands it is asked to run and answers them with canned output, and a
+// PATH holding only the executables a test puts on it.
+package platformtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/jschneider/agenthelper/internal/platform"
+)
+
+// Response is the canned result of a command
+type Response struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Then     func() // runs after the command, e.g. to script the next answer of another command
+}
+
+// ExitError is the error of a command that exits non-zero
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string { return fmt.Sprintf("exit status %d", e.Code) }
+
+// ExitCode returns the exit code, as *exec.ExitError does
+func (e *ExitError) ExitCode() int { return e.Code }
+
+type rule struct {
+	prefix   string
+	response Response
+}
+
+// Runner is a platform.Runner that answers command lines from a script. Commands that
+// match no rule fail with exit code 127, as a missing command would.
+type Runner struct {
+	mu    sync.Mutex
+	rules []rule
+	calls []string
+}
+
+// On answers command lines starting with prefix. Later rules take precedence, so a test
+// can change the answer of a command while it runs.
+func (r *Runner) On(prefix string, response Response) *Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append(r.rules, rule{prefix: prefix, response: response})
+	return r
+}
+
+// Run records the command line and answers it
+func (r *Runner) Run(ctx context.Context, cmd *platform.Command) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.calls = append(r.calls, cmd.Line)
+	response, ok := r.match(cmd.Line)
+	r.mu.Unlock()
+
+	if !ok {
+		response = Response{Stderr: cmd.Line + ": command not found\n", ExitCode: 127}
+	}
+	if cmd.Stdout != nil {
+		io.WriteString(cmd.Stdout, response.Stdout)
+	}
+	if cmd.Stderr != nil {
+		io.WriteString(cmd.Stderr, response.Stderr)
+	}
+	if response.Then != nil {
+		response.Then()
+	}
+	if response.ExitCode != 0 {
+		return &ExitError{Code: response.ExitCode}
+	}
+	return nil
+}
+
+func (r *Runner) match(line string) (Response, bool) {
+	for i := len(r.rules) - 1; i >= 0; i-- {
+		if strings.HasPrefix(line, r.rules[i].prefix) {
+			return r.rules[i].response, true
+		}
+	}
+	return Response{}, false
+}
+
+// Calls returns the command lines run so far, in order
+func (r *Runner) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+// CallsMatching returns the command lines run so far that start with prefix
+func (r *Runner) CallsMatching(prefix string) []string {
+	var matching []string
+	for _, call := range r.Calls() {
+		if strings.HasPrefix(call, prefix) {
+			matching = append(matching, call)
+		}
+	}
+	return matching
+}
+
+// Path is a platform.PathLookup over a fixed set of executables: the copies of each
+// name in PATH order
+type Path map[string][]string
+
+// LookPath returns the path of the first copy of an executable put on the PATH
+func (p Path) LookPath(name string) (string, error) {
+	if paths := p[name]; len(paths) > 0 {
+		return paths[0], nil
+	}
+	return "", fmt.Errorf("exec: %q: executable file not found in $PATH", name)
+}
+
+// FindAll returns every copy of an executable put on the PATH
+func (p Path) FindAll(name string) []string {
+	return append([]string(nil), p[name]...)
+}
+
+// NewSystem returns a simulated system of the given OS with the named executables on its
+// PATH, and the runner answering its commands
+func NewSystem(os platform.OS, executables ...string) (*platform.System, *Runner) {
+	path := make(Path)
+	for _, name := range executables {
+		if os == platform.Windows {
+			path[name] = []string{`C:\platformtest\bin\` + name + ".exe"}
+		} else {
+			path[name] = []string{"/platformtest/bin/" + name}
+		}
+	}
+	runner := &Runner{}
+	return &platform.System{OS: os, Runner: runner, Path: path}, runner
+}
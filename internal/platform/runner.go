@@ -0,0 +1,102 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Command is a shell command line together with its environment and I/O
+type Command struct {
+	Line   string
+	Env    []string // the complete environment when set, else the current one
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Runner runs shell command lines. Errors of commands that ran but failed have an
+// ExitCode method, as *exec.ExitError does.
+type Runner interface {
+	Run(ctx context.Context, cmd *Command) error
+}
+
+// PathLookup finds executables on PATH
+type PathLookup interface {
+	LookPath(name string) (string, error)
+	// FindAll returns every copy of an executable on PATH in PATH order, the one that
+	// runs first
+	FindAll(name string) []string
+}
+
+// ShellRunner runs commands through the platform shell, without a console window on
+// Windows
+type ShellRunner struct{}
+
+// Run runs the command and waits for it; it is killed when the context is done
+func (ShellRunner) Run(ctx context.Context, c *Command) error {
+	cmd := NewShellCommandContext(ctx, c.Line)
+	cmd.Env = c.Env
+	cmd.Stdin = c.Stdin
+	cmd.Stdout = c.Stdout
+	cmd.Stderr = c.Stderr
+	return cmd.Run()
+}
+
+// SystemPath looks up executables on the PATH of this process
+type SystemPath struct{}
+
+// LookPath returns the path of the executable that runs for name
+func (SystemPath) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
+
+// FindAll returns every copy of an executable on PATH, see FindExecutables
+func (SystemPath) FindAll(name string) []string {
+	return FindExecutables(name)
+}
+
+// System is the machine that tools are managed on: the OS whose conventions apply, how
+// commands run and how executables are found. Tests replace all three.
+type System struct {
+	OS     OS
+	Runner Runner
+	Path   PathLookup
+}
+
+// LocalSystem returns the machine this process runs on
+func LocalSystem() *System {
+	return &System{OS: Current().OS, Runner: ShellRunner{}, Path: SystemPath{}}
+}
+
+// Platform returns the platform of the system, with the architecture of this process
+func (s *System) Platform() *Platform {
+	p := Current()
+	if p.OS != s.OS {
+		p = &Platform{OS: s.OS, Arch: p.Arch, OSString: string(s.OS)}
+	}
+	return p
+}
+
+// Output runs a command line and returns its trimmed stdout
+func (s *System) Output(ctx context.Context, line string) (string, error) {
+	var stdout bytes.Buffer
+	err := s.Runner.Run(ctx, &Command{Line: line, Stdout: &stdout})
+	return strings.TrimSpace(stdout.String()), err
+}
+
+// HasCommand reports whether an executable is on PATH
+func (s *System) HasCommand(name string) bool {
+	_, err := s.Path.LookPath(name)
+	return err == nil
+}
+
+// QuoteArg quotes a word, such as a path, for the shell of the system
+func (s *System) QuoteArg(arg string) string {
+	if s.OS == Windows {
+		return `"` + arg + `"`
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
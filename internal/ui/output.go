@@ -29,6 +29,9 @@ var (
 
 	// Color mode
 	colorEnabled = true
+
+	// Quiet mode keeps stdout free for machine-readable output
+	quietMode = false
 )
 
 // SetDebugMode enables or disables debug output
@@ -36,6 +39,11 @@ func SetDebugMode(enabled bool) {
 	debugMode = enabled
 }
 
+// SetQuietMode suppresses everything but errors on the terminal
+func SetQuietMode(enabled bool) {
+	quietMode = enabled
+}
+
 // SetColorEnabled enables or disables colored output
 func SetColorEnabled(enabled bool) {
 	colorEnabled = enabled
@@ -49,6 +57,9 @@ func IsColorEnabled() bool {
 
 // Success prints a success message
 func Success(format string, a ...interface{}) {
+	if quietMode {
+		return
+	}
 	fmt.Fprintf(os.Stdout, "%s %s\n", successColor(SymbolSuccess), fmt.Sprintf(format, a...))
 }
 
@@ -59,11 +70,17 @@ func Error(format string, a ...interface{}) {
 
 // Warn prints a warning message
 func Warn(format string, a ...interface{}) {
+	if quietMode {
+		return
+	}
 	fmt.Fprintf(os.Stdout, "%s %s\n", warnColor(SymbolWarn), fmt.Sprintf(format, a...))
 }
 
 // Info prints an info message
 func Info(format string, a ...interface{}) {
+	if quietMode {
+		return
+	}
 	fmt.Fprintf(os.Stdout, "%s %s\n", infoColor(SymbolInfo), fmt.Sprintf(format, a...))
 }
 
@@ -76,6 +93,9 @@ func Debug(format string, a ...interface{}) {
 
 // Print prints a message without prefix
 func Print(format string, a ...interface{}) {
+	if quietMode {
+		return
+	}
 	fmt.Fprintf(os.Stdout, "%s\n", fmt.Sprintf(format, a...))
 }
 
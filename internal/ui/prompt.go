@@ -58,9 +58,10 @@ func ShowCommandHelp() {
 	}{
 		{"/help", "Show this help message"},
 		{"/status", "Refresh the tool status table"},
-		{"/install <tool>", "Install a specific tool"},
-		{"/update [tool]", "Update all tools or a specific tool"},
+		{"/install <tool>[@ver]", "Install a specific tool"},
+		{"/update [tool[@ver]]", "Update all tools or a specific tool"},
 		{"/repair <tool>", "Uninstall and reinstall a tool"},
+		{"/uninstall <tool>", "Uninstall a tool (--purge deletes its config)"},
 		{"/run <tool>", "Launch a tool"},
 		{"/env", "Show environment report"},
 		{"/exit", "Exit AgentHelper (or Ctrl+C)"},
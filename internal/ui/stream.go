@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	// streamMu keeps lines of concurrently streamed commands from mixing
+	streamMu sync.Mutex
+	// openLine is the writer whose last line has not been terminated yet
+	openLine *PrefixWriter
+)
+
+// PrefixWriter shows command output on the terminal with a prefix on every line.
+// Partial lines are written immediately so that prompts stay visible; when another
+// writer interrupts a partial line, the line is ended and continued under a new prefix.
+type PrefixWriter struct {
+	out     io.Writer
+	prefix  string
+	midLine bool
+}
+
+// NewPrefixWriter creates a writer that streams to stdout, prefixing lines with the label
+func NewPrefixWriter(label string) *PrefixWriter {
+	return &PrefixWriter{
+		out:    os.Stdout,
+		prefix: debugColor(label+" │") + " ",
+	}
+}
+
+// Write implements io.Writer
+func (w *PrefixWriter) Write(p []byte) (int, error) {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+
+	var b strings.Builder
+	if openLine != nil && openLine != w {
+		b.WriteString("\n")
+		openLine.midLine = false
+		openLine = nil
+	}
+
+	// Progress bars redraw a line with \r; show each redraw as its own line
+	text := strings.ReplaceAll(string(p), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	for text != "" {
+		line, rest, found := strings.Cut(text, "\n")
+		text = rest
+		if !w.midLine {
+			if line == "" && found {
+				continue // Blank lines carry no progress information
+			}
+			b.WriteString(w.prefix)
+		}
+		b.WriteString(line)
+		if found {
+			b.WriteString("\n")
+		}
+		w.midLine = !found
+	}
+
+	if w.midLine {
+		openLine = w
+	} else if openLine == w {
+		openLine = nil
+	}
+
+	if _, err := io.WriteString(w.out, b.String()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close ends a partial last line
+func (w *PrefixWriter) Close() error {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+
+	if openLine == w {
+		openLine = nil
+	}
+	if w.midLine {
+		w.midLine = false
+		_, err := io.WriteString(w.out, "\n")
+		return err
+	}
+	return nil
+}
+
+// IsTerminal reports whether the file is an interactive terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
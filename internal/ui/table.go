@@ -58,13 +58,14 @@ func (t *Table) SetHeaderColor(colors ...tablewriter.Colors) {
 
 // StatusTable creates a pre-configured table for tool status display
 func StatusTable() *Table {
-	t := NewTable([]string{"Tool", "Status", "Installed", "Latest", "Command"})
+	t := NewTable([]string{"Tool", "Status", "Installed", "Via", "Latest", "Command"})
 	if IsColorEnabled() {
 		t.table.SetColumnColor(
 			tablewriter.Colors{tablewriter.Bold},
 			tablewriter.Colors{},
 			tablewriter.Colors{},
 			tablewriter.Colors{},
+			tablewriter.Colors{},
 			tablewriter.Colors{tablewriter.FgHiBlackColor},
 		)
 	}